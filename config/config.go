@@ -1,9 +1,76 @@
 package config
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Config struct {
 	Enabled  bool
 	Provider string
 	Owner    string
 	Env      string
 	Token    string
+	// TokenFile is a file holding Token, e.g. a mounted secret, read again once it changes
+	TokenFile string
+	// BaseURL is the API URL of the provider, empty for the provider's public instance
+	BaseURL string
+	// Providers are the providers the composite provider merges the deployments of
+	Providers []string
+	// ProviderSettings holds the settings specific to a provider by provider name, each decoded by its
+	// provider into its own settings struct with external_deployments.DecodeSettings
+	ProviderSettings map[string]json.RawMessage
+
+	// Tokens are further GitHub tokens pooled with Token and the app, each request is sent with
+	// the credential with the most remaining rate limit
+	Tokens []string
+	// AppID authenticates with GitHub as this GitHub App in addition to the tokens,
+	// with the private key in the PEM file AppPrivateKeyPath
+	AppID             int64
+	AppPrivateKeyPath string
+	// AppInstallationID fixes the installation of the app, by default the installation of the repository's owner is used
+	AppInstallationID int64
+
+	// UploadURL is the upload URL of GitHub Enterprise Server, by default derived from BaseURL
+	UploadURL string
+	// CABundlePath is a PEM file of CA certificates trusted in addition to the system ones
+	CABundlePath string
+	// ProxyURL is the proxy of requests to GitHub, by default the one of HTTPS_PROXY
+	ProxyURL string
+	// ConnectTimeout limits establishing connections including the TLS handshake
+	ConnectTimeout time.Duration
+	// RequestTimeout limits waiting for the response headers of each attempt of a request
+	RequestTimeout time.Duration
+
+	// KubeConfig is the kubeconfig file of the cluster the workloads are read from, empty to use the cluster it runs in
+	KubeConfig string
+
+	// CacheSize is the maximum number of repositories whose deployments are cached
+	CacheSize int
+	// CacheTTL is the time after which the cached deployments of a repository are dropped
+	CacheTTL time.Duration
+	// CachePath is the file the cache is persisted in, the cache is kept in memory only if empty
+	CachePath string
+
+	// RateLimitWait makes requests wait for the reset of an exhausted rate limit instead of failing
+	RateLimitWait bool
+	// RateLimitMaxWait is the longest time a request waits for a rate limit reset
+	RateLimitMaxWait time.Duration
+	// MaxRetries is the number of retries of rate limited and transient failed requests, negative disables retries
+	MaxRetries int
+	// MaxConcurrency is the maximum number of concurrent requests for deployment statuses and comparisons
+	MaxConcurrency int
+	// LoadMargin is how long before the start of a query deployments are loaded,
+	// deployments created before the query might succeed within it
+	LoadMargin time.Duration
+
+	// Repositories configures repositories of other owners, environments or providers,
+	// and the workloads deployed from them
+	Repositories []Repository
+	// WorkloadMapping are the rules mapping workloads to repositories, evaluated in order after the workloads
+	// listed by Repositories and before the default dropping a version suffix like -v2 from the workload name
+	WorkloadMapping []MappingRule
+	// WorkloadAnnotations reads the repository and environment of a workload from the annotations
+	// github.com/repo and github.com/environment of its Deployment or StatefulSet, before any other mapping rule
+	WorkloadAnnotations bool
 }
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Repository configures a repository deviating from the defaults of the Config, and the workloads deployed from it.
+// Empty fields are the ones of the Config.
+type Repository struct {
+	Owner       string `yaml:"owner"`
+	Name        string `yaml:"name"`
+	Environment string `yaml:"environment"`
+	Provider    string `yaml:"provider"`
+	// Workloads are the names of the workloads deployed from the repository
+	Workloads []string `yaml:"workloads"`
+}
+
+// MappingRule maps workloads to a repository, it sets one of Workload, Match, Label and Annotation
+type MappingRule struct {
+	// Workload is the name, or namespace/name, of a workload deployed from Repository
+	Workload string `yaml:"workload"`
+	// Match is a regular expression matching workload names, Repository may refer to its capture groups like $1
+	Match string `yaml:"match"`
+	// Label is a label of the Deployment or StatefulSet whose value is the repository, e.g. app.kubernetes.io/part-of
+	Label string `yaml:"label"`
+	// Annotation is an annotation of the Deployment or StatefulSet whose value is the repository or its URL
+	Annotation string `yaml:"annotation"`
+	// Repository is the repository, or owner/repository, of the matching workloads
+	Repository string `yaml:"repository"`
+}
+
+// file is the layout of a config file, groups of settings are nested. The sections of the built-in providers
+// like argocd are short for their entry of providerSettings.
+type file struct {
+	Enabled          bool                `yaml:"enabled"`
+	Provider         string              `yaml:"provider"`
+	Owner            string              `yaml:"owner"`
+	Environment      string              `yaml:"environment"`
+	Token            string              `yaml:"token"`
+	TokenFile        string              `yaml:"tokenFile"`
+	Tokens           []string            `yaml:"tokens"`
+	BaseURL          string              `yaml:"baseURL"`
+	UploadURL        string              `yaml:"uploadURL"`
+	CABundlePath     string              `yaml:"caBundlePath"`
+	ProxyURL         string              `yaml:"proxyURL"`
+	ConnectTimeout   time.Duration       `yaml:"connectTimeout"`
+	RequestTimeout   time.Duration       `yaml:"requestTimeout"`
+	Providers        []string            `yaml:"providers"`
+	ProviderSettings map[string]settings `yaml:"providerSettings"`
+	KubeConfig       string              `yaml:"kubeConfig"`
+
+	App struct {
+		ID             int64  `yaml:"id"`
+		PrivateKeyPath string `yaml:"privateKeyPath"`
+		InstallationID int64  `yaml:"installationID"`
+	} `yaml:"app"`
+	ArgoCD settings `yaml:"argocd"`
+	Flux   settings `yaml:"flux"`
+	Git    settings `yaml:"git"`
+	File   settings `yaml:"file"`
+	Cache  struct {
+		Size int           `yaml:"size"`
+		TTL  time.Duration `yaml:"ttl"`
+		Path string        `yaml:"path"`
+	} `yaml:"cache"`
+	RateLimit struct {
+		Wait           bool          `yaml:"wait"`
+		MaxWait        time.Duration `yaml:"maxWait"`
+		MaxRetries     int           `yaml:"maxRetries"`
+		MaxConcurrency int           `yaml:"maxConcurrency"`
+		LoadMargin     time.Duration `yaml:"loadMargin"`
+	} `yaml:"rateLimit"`
+
+	Repositories        []Repository  `yaml:"repositories"`
+	WorkloadMapping     []MappingRule `yaml:"workloadMapping"`
+	WorkloadAnnotations bool          `yaml:"workloadAnnotations"`
+}
+
+// KeyError is an invalid key or value of a config file
+type KeyError struct {
+	// Key is the path of the key, e.g. repositories[1].name
+	Key  string
+	Line int
+	Err  error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Key, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// Load reads the YAML or JSON config file at path. Unknown keys and invalid values are reported as errors,
+// invalid settings as *KeyError.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error while parsing config file %s: %w", path, err)
+	}
+
+	f := file{
+		Enabled:  true,
+		Provider: "github",
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error in config file %s: %w", path, err)
+	}
+
+	lines := make(keyLines)
+	lines.add(&root, "")
+	if err := lines.validate(&f); err != nil {
+		return nil, fmt.Errorf("error in config file %s: %w", path, err)
+	}
+
+	var providerSettings map[string]json.RawMessage
+	for name, raw := range f.ProviderSettings {
+		if providerSettings == nil {
+			providerSettings = make(map[string]json.RawMessage)
+		}
+		providerSettings[name] = json.RawMessage(raw)
+	}
+	for _, section := range []struct {
+		name string
+		raw  settings
+	}{{"argocd", f.ArgoCD}, {"flux", f.Flux}, {"git", f.Git}, {"file", f.File}} {
+		name, raw := section.name, section.raw
+		if raw == nil {
+			continue
+		}
+		if _, ok := providerSettings[name]; ok {
+			return nil, fmt.Errorf("error in config file %s: %w", path, lines.errorf(name, "already set by providerSettings.%s", name))
+		}
+		if providerSettings == nil {
+			providerSettings = make(map[string]json.RawMessage)
+		}
+		providerSettings[name] = json.RawMessage(raw)
+	}
+
+	return &Config{
+		Enabled:             f.Enabled,
+		Provider:            f.Provider,
+		Owner:               f.Owner,
+		Env:                 f.Environment,
+		Token:               f.Token,
+		TokenFile:           f.TokenFile,
+		BaseURL:             f.BaseURL,
+		Providers:           f.Providers,
+		ProviderSettings:    providerSettings,
+		Tokens:              f.Tokens,
+		AppID:               f.App.ID,
+		AppPrivateKeyPath:   f.App.PrivateKeyPath,
+		AppInstallationID:   f.App.InstallationID,
+		UploadURL:           f.UploadURL,
+		CABundlePath:        f.CABundlePath,
+		ProxyURL:            f.ProxyURL,
+		ConnectTimeout:      f.ConnectTimeout,
+		RequestTimeout:      f.RequestTimeout,
+		KubeConfig:          f.KubeConfig,
+		CacheSize:           f.Cache.Size,
+		CacheTTL:            f.Cache.TTL,
+		CachePath:           f.Cache.Path,
+		RateLimitWait:       f.RateLimit.Wait,
+		RateLimitMaxWait:    f.RateLimit.MaxWait,
+		MaxRetries:          f.RateLimit.MaxRetries,
+		MaxConcurrency:      f.RateLimit.MaxConcurrency,
+		LoadMargin:          f.RateLimit.LoadMargin,
+		Repositories:        f.Repositories,
+		WorkloadMapping:     f.WorkloadMapping,
+		WorkloadAnnotations: f.WorkloadAnnotations,
+	}, nil
+}
+
+// settings is the JSON encoding of the settings of a provider, they may be written in YAML
+type settings json.RawMessage
+
+func (s *settings) UnmarshalYAML(node *yaml.Node) error {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*s = raw
+	return nil
+}
+
+// keyLines maps the keys of a config file, e.g. repositories[1].name, to the line of their value
+type keyLines map[string]int
+
+// add adds the keys of node, whose key is key
+func (l keyLines) add(node *yaml.Node, key string) {
+	l[key] = node.Line
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			l.add(child, key)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.add(node.Content[i+1], joinKey(key, node.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			l.add(item, fmt.Sprintf("%s[%d]", key, i))
+		}
+	}
+}
+
+// validate checks the settings depending on each other
+func (l keyLines) validate(f *file) error {
+	if f.App.ID != 0 && f.App.PrivateKeyPath == "" {
+		return l.errorf("app.privateKeyPath", "required with app.id")
+	}
+	if f.Provider == "composite" && len(f.Providers) == 0 {
+		return l.errorf("providers", "required with provider composite")
+	}
+
+	repos := make(map[string]string)
+	workloads := make(map[string]string)
+	for i, repo := range f.Repositories {
+		key := fmt.Sprintf("repositories[%d]", i)
+		if repo.Name == "" {
+			return l.errorf(key+".name", "required")
+		}
+		if strings.Contains(repo.Name, "/") {
+			return l.errorf(key+".name", "must not contain /, the owner is set by owner")
+		}
+
+		owner := repo.Owner
+		if owner == "" {
+			owner = f.Owner
+		}
+		if other, ok := repos[owner+"/"+repo.Name]; ok {
+			return l.errorf(key, "%s/%s is already configured by %s", owner, repo.Name, other)
+		}
+		repos[owner+"/"+repo.Name] = key
+
+		for j, workload := range repo.Workloads {
+			workloadKey := fmt.Sprintf("%s.workloads[%d]", key, j)
+			if workload == "" {
+				return l.errorf(workloadKey, "must not be empty")
+			}
+			if other, ok := workloads[workload]; ok {
+				return l.errorf(workloadKey, "workload %s is already mapped by %s", workload, other)
+			}
+			workloads[workload] = workloadKey
+		}
+	}
+
+	for i, rule := range f.WorkloadMapping {
+		key := fmt.Sprintf("workloadMapping[%d]", i)
+		set := 0
+		for _, v := range []string{rule.Workload, rule.Match, rule.Label, rule.Annotation} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return l.errorf(key, "requires exactly one of workload, match, label and annotation")
+		}
+		if rule.Match != "" {
+			if _, err := regexp.Compile(rule.Match); err != nil {
+				return l.errorf(key+".match", "invalid regular expression: %v", err)
+			}
+		}
+		if (rule.Label != "" || rule.Annotation != "") && rule.Repository != "" {
+			return l.errorf(key+".repository", "not allowed with label or annotation, the repository is their value")
+		}
+		if rule.Label == "" && rule.Annotation == "" && rule.Repository == "" {
+			return l.errorf(key+".repository", "required")
+		}
+	}
+	return nil
+}
+
+// errorf returns a *KeyError of key at its line, or the line of its closest parent if key is missing
+func (l keyLines) errorf(key, format string, args ...any) error {
+	line, parent := 0, key
+	for {
+		if n, ok := l[parent]; ok {
+			line = n
+			break
+		}
+		i := strings.LastIndexAny(parent, ".[")
+		if i < 0 {
+			line = l[""]
+			break
+		}
+		parent = parent[:i]
+	}
+	return &KeyError{Key: key, Line: line, Err: fmt.Errorf(format, args...)}
+}
+
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// Repository returns the configured repository owner/name, nil if it is not configured
+func (c *Config) Repository(owner, name string) *Repository {
+	for i, repo := range c.Repositories {
+		if repo.Name == name && c.OwnerOf(&c.Repositories[i]) == owner {
+			return &c.Repositories[i]
+		}
+	}
+	return nil
+}
+
+// OwnerOf returns the owner of repo, the configured owner if repo has none
+func (c *Config) OwnerOf(repo *Repository) string {
+	if repo.Owner == "" {
+		return c.Owner
+	}
+	return repo.Owner
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `owner: acme
+tokenFile: /var/run/secrets/github/token
+rateLimit:
+  maxWait: 90s
+cache:
+  ttl: 5m
+providerSettings:
+  jenkins:
+    url: https://jenkins.example.com
+    jobs: [build, deploy]
+repositories:
+  - name: shop
+    environment: staging
+    workloads: [shop, shop-worker]
+workloadMapping:
+  - match: ^(.*)-api$
+    repository: $1
+`)
+
+	conf, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !conf.Enabled || conf.Provider != "github" {
+		t.Errorf("Enabled, Provider = %v, %q, want the defaults true, github", conf.Enabled, conf.Provider)
+	}
+	if conf.Owner != "acme" || conf.TokenFile != "/var/run/secrets/github/token" {
+		t.Errorf("Owner, TokenFile = %q, %q", conf.Owner, conf.TokenFile)
+	}
+	if conf.RateLimitMaxWait != 90*time.Second || conf.CacheTTL != 5*time.Minute {
+		t.Errorf("RateLimitMaxWait, CacheTTL = %v, %v, want 1m30s, 5m0s", conf.RateLimitMaxWait, conf.CacheTTL)
+	}
+	if got, want := string(conf.ProviderSettings["jenkins"]), `{"jobs":["build","deploy"],"url":"https://jenkins.example.com"}`; got != want {
+		t.Errorf("ProviderSettings[jenkins] = %s, want %s", got, want)
+	}
+	if repo := conf.Repository("acme", "shop"); repo == nil || repo.Environment != "staging" || len(repo.Workloads) != 2 {
+		t.Errorf("Repository(acme, shop) = %+v", repo)
+	}
+	if len(conf.WorkloadMapping) != 1 || conf.WorkloadMapping[0].Repository != "$1" {
+		t.Errorf("WorkloadMapping = %+v", conf.WorkloadMapping)
+	}
+}
+
+func TestLoadProviderSections(t *testing.T) {
+	path := writeConfig(t, `kubeConfig: /etc/kube/config
+argocd:
+  url: https://argocd.example.com
+  tokenFile: /var/run/secrets/argocd/token
+file:
+  path: deployments.json
+providerSettings:
+  jenkins:
+    url: https://jenkins.example.com
+`)
+
+	conf, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if conf.KubeConfig != "/etc/kube/config" {
+		t.Errorf("KubeConfig = %q, want /etc/kube/config", conf.KubeConfig)
+	}
+	want := map[string]string{
+		"argocd":  `{"tokenFile":"/var/run/secrets/argocd/token","url":"https://argocd.example.com"}`,
+		"file":    `{"path":"deployments.json"}`,
+		"jenkins": `{"url":"https://jenkins.example.com"}`,
+	}
+	if len(conf.ProviderSettings) != len(want) {
+		t.Errorf("ProviderSettings = %s, want the providers of %v", conf.ProviderSettings, want)
+	}
+	for name, settings := range want {
+		if got := string(conf.ProviderSettings[name]); got != settings {
+			t.Errorf("ProviderSettings[%s] = %s, want %s", name, got, settings)
+		}
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	conf, err := Load(writeConfig(t, ""))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !conf.Enabled || conf.Provider != "github" {
+		t.Errorf("Enabled, Provider = %v, %q, want the defaults true, github", conf.Enabled, conf.Provider)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		wantKey bool
+	}{
+		{
+			name:    "unknown key",
+			content: "owner: acme\nrateLimit:\n  maxwait: 1m\n",
+			wantErr: "line 3: field maxwait not found",
+		},
+		{
+			name:    "invalid duration",
+			content: "cache:\n  ttl: 5 minutes\n",
+			wantErr: "line 2: cannot unmarshal !!str `5 minutes` into time.Duration",
+		},
+		{
+			name:    "invalid integer",
+			content: "app:\n  id: acme\n",
+			wantErr: "line 2: cannot unmarshal !!str `acme` into int64",
+		},
+		{
+			name:    "missing repository name",
+			content: "owner: acme\nrepositories:\n  - name: shop\n  - environment: staging\n",
+			wantErr: "line 4: repositories[1].name: required",
+			wantKey: true,
+		},
+		{
+			name:    "workload mapped twice",
+			content: "repositories:\n  - name: shop\n    workloads: [shop]\n  - name: cart\n    workloads:\n      - cart\n      - shop\n",
+			wantErr: "line 7: repositories[1].workloads[1]: workload shop is already mapped by repositories[0].workloads[0]",
+			wantKey: true,
+		},
+		{
+			name:    "provider section and settings",
+			content: "git:\n  repoPath: /srv/git\nproviderSettings:\n  git:\n    repoPath: /srv/other\n",
+			wantErr: "line 2: git: already set by providerSettings.git",
+			wantKey: true,
+		},
+		{
+			name:    "app without private key",
+			content: "app:\n  id: 42\n",
+			wantErr: "line 2: app.privateKeyPath: required with app.id",
+			wantKey: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil {
+				t.Fatal("Load() error = nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+			var keyErr *KeyError
+			if errors.As(err, &keyErr) != tt.wantKey {
+				t.Errorf("Load() error is *KeyError = %v, want %v", !tt.wantKey, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/github"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+// API mock for testing
+type API interface {
+	GetApplication(ctx context.Context, name string) (*Application, error)
+}
+
+// ErrorResponse is returned for API responses with an error status
+type ErrorResponse struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("argo cd API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Settings are the provider settings argocd of the config
+type Settings struct {
+	URL       string `json:"url"`
+	Token     string `json:"token"`
+	TokenFile string `json:"tokenFile"`
+}
+
+type Client struct {
+	client  *http.Client
+	baseURL *url.URL
+	token   secret.Secret
+}
+
+func NewAPI(conf *config.Config, settings Settings) (API, error) {
+	if len(settings.URL) == 0 {
+		return nil, fmt.Errorf("no argo cd URL provided")
+	}
+	if len(settings.Token) == 0 && len(settings.TokenFile) == 0 {
+		return nil, fmt.Errorf("no argo cd auth token provided")
+	}
+	token, err := secret.New(settings.Token, settings.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := github.NewHTTPTransport(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewArgoCDClient(&http.Client{Transport: transport}, settings.URL, token)
+}
+
+func NewArgoCDClient(client *http.Client, baseURL string, token secret.Secret) (API, error) {
+	if client == nil {
+		return nil, fmt.Errorf("http client cannot be nil")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argo cd URL %s: %w", baseURL, err)
+	}
+	return &Client{
+		client:  client,
+		baseURL: u,
+		token:   token,
+	}, nil
+}
+
+func (ac *Client) GetApplication(ctx context.Context, name string) (*Application, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getApplication took %v\n", time.Since(start))
+	}()
+
+	u := ac.baseURL.JoinPath("api", "v1", "applications", name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := ac.token.Value()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := ac.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, &ErrorResponse{StatusCode: resp.StatusCode, Message: body.Message}
+	}
+
+	var app Application
+	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return nil, fmt.Errorf("error while decoding argo cd application %s: %w", name, err)
+	}
+	return &app, nil
+}
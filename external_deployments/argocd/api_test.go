@@ -0,0 +1,61 @@
+package argocd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+)
+
+func TestNewAPIAppliesHTTPSettings(t *testing.T) {
+	t.Run("proxy", func(t *testing.T) {
+		var host string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host = r.Host
+			_, _ = w.Write([]byte("{}"))
+		}))
+		t.Cleanup(proxy.Close)
+
+		api, err := NewAPI(&config.Config{ProxyURL: proxy.URL}, Settings{URL: "http://argocd.example.com", Token: "argo-token"})
+		if err != nil {
+			t.Fatalf("NewAPI() error = %v", err)
+		}
+		if _, err := api.GetApplication(context.Background(), "shop"); err != nil {
+			t.Fatalf("GetApplication() error = %v", err)
+		}
+		if host != "argocd.example.com" {
+			t.Errorf("proxied request to %q, want argocd.example.com", host)
+		}
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		api, err := NewAPI(&config.Config{RequestTimeout: 20 * time.Millisecond}, Settings{URL: server.URL, Token: "argo-token"})
+		if err != nil {
+			t.Fatalf("NewAPI() error = %v", err)
+		}
+		start := time.Now()
+		if _, err := api.GetApplication(context.Background(), "shop"); err == nil {
+			t.Error("GetApplication() error = nil, want the request timed out")
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("failed after %v, want after the request timeout", elapsed)
+		}
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		if _, err := NewAPI(&config.Config{CABundlePath: "/nonexistent/ca.pem"}, Settings{URL: "https://argocd.example.com", Token: "argo-token"}); err == nil {
+			t.Error("NewAPI() error = nil, want the missing CA bundle reported")
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+// CommitComparer populates deployments with the commits added and removed compared to the next older one,
+// github.DeploymentClient implements it
+type CommitComparer interface {
+	PopulateWithCommits(ctx context.Context, owner, repo string, deployments []*model.Deployment) ([]*model.Deployment, error)
+}
+
+// DeploymentClient reads the sync history of Argo CD applications, every history entry is a successful
+// deployment of its revision. The commits are compared in the repository of the application's source.
+// It is safe for concurrent use.
+type DeploymentClient struct {
+	api      API
+	comparer CommitComparer
+}
+
+func NewDeploymentClient(api API, comparer CommitComparer) (*DeploymentClient, error) {
+	if api == nil {
+		return nil, fmt.Errorf("api cannot be nil")
+	}
+	if comparer == nil {
+		return nil, fmt.Errorf("comparer cannot be nil")
+	}
+	return &DeploymentClient{
+		api:      api,
+		comparer: comparer,
+	}, nil
+}
+
+// Close closes the comparer if it holds resources, e.g. the store of the GitHub client
+func (adc *DeploymentClient) Close() error {
+	if closer, ok := adc.comparer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ListDeploymentsInRange lists the syncs of the application named like q.Workload, or q.Repo if no workload
+// is set, which finished in range [q.From, q.To]
+func (adc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	name := q.Workload
+	if name == "" {
+		name = q.Repo
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no application set in query")
+	}
+
+	app, err := adc.api.GetApplication(ctx, name)
+	if err != nil {
+		var argoErr *ErrorResponse
+		// Argo CD answers 403 for applications which do not exist, to not leak their names
+		if errors.As(err, &argoErr) && (argoErr.StatusCode == http.StatusNotFound || argoErr.StatusCode == http.StatusForbidden) {
+			return nil, fmt.Errorf("%w: argo cd application %s", model.ErrRepositoryNotFound, name)
+		}
+		return nil, err
+	}
+
+	owner, repo, ok := model.ParseRepoURL(app.RepoURL())
+	if !ok {
+		owner, repo = q.Owner, q.Repo
+	}
+
+	successful := toDeployments(app.Status.History)
+	slices.SortFunc(successful, func(a, b *model.Deployment) int {
+		return b.SucceededAt.Compare(a.SucceededAt)
+	})
+
+	inRange, oneBefore := model.SplitRange(successful, q.From, q.To)
+
+	if oneBefore != nil {
+		inRange = append(inRange, oneBefore)
+	}
+
+	populated, err := adc.comparer.PopulateWithCommits(ctx, owner, repo, inRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove one before
+	if oneBefore != nil {
+		populated = populated[:len(populated)-1]
+	}
+	return populated, nil
+}
@@ -0,0 +1,141 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+var baseTime = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+// fakeComparer records the compared deployments and links each one to the next older one
+type fakeComparer struct {
+	owner, repo string
+	compared    []int64
+	closed      bool
+}
+
+func (f *fakeComparer) PopulateWithCommits(_ context.Context, owner, repo string, deployments []*model.Deployment) ([]*model.Deployment, error) {
+	f.owner, f.repo = owner, repo
+	for i, d := range deployments {
+		f.compared = append(f.compared, d.ID)
+		if i+1 < len(deployments) {
+			d.ComparisonURL = deployments[i+1].SHA + "..." + d.SHA
+		}
+	}
+	return deployments, nil
+}
+
+func (f *fakeComparer) Close() error {
+	f.closed = true
+	return nil
+}
+
+// newArgoCD serves the application shop synced four times an hour apart, other applications are forbidden
+func newArgoCD(t *testing.T) *httptest.Server {
+	t.Helper()
+	app := Application{
+		Metadata: ObjectMeta{Name: "shop", Namespace: "argocd"},
+		Spec:     ApplicationSpec{Source: &ApplicationSource{RepoURL: "https://github.com/acme/storefront.git"}},
+	}
+	for id := range int64(4) {
+		app.Status.History = append(app.Status.History, RevisionHistory{
+			ID:         id,
+			Revision:   fmt.Sprintf("sha%d", id),
+			DeployedAt: baseTime.Add(time.Duration(id) * time.Hour),
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer argo-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/v1/applications/shop" {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "permission denied"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(app)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, comparer CommitComparer) *DeploymentClient {
+	t.Helper()
+	api, err := NewArgoCDClient(http.DefaultClient, newArgoCD(t).URL, secret.Static("argo-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewDeploymentClient(api, comparer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestListDeploymentsInRange(t *testing.T) {
+	comparer := &fakeComparer{}
+	client := newTestClient(t, comparer)
+
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Workload: "shop",
+		From:     baseTime.Add(30 * time.Minute),
+		To:       baseTime.Add(150 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	for _, d := range deployments {
+		got = append(got, d.ID)
+	}
+	if want := []int64{2, 1}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+	if deployments[1].ComparisonURL != "sha0...sha1" {
+		t.Errorf("comparison of the oldest deployment = %q, want it compared to the sync before the range", deployments[1].ComparisonURL)
+	}
+	if comparer.owner != "acme" || comparer.repo != "storefront" {
+		t.Errorf("compared in %s/%s, want the source repository acme/storefront", comparer.owner, comparer.repo)
+	}
+	if want := []int64{2, 1, 0}; !slices.Equal(comparer.compared, want) {
+		t.Errorf("compared %v, want %v", comparer.compared, want)
+	}
+}
+
+func TestListDeploymentsInRangeOfUnknownApplication(t *testing.T) {
+	client := newTestClient(t, &fakeComparer{})
+
+	_, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Workload: "unknown",
+		From:     baseTime,
+		To:       baseTime.Add(time.Hour),
+	})
+	if !errors.Is(err, model.ErrRepositoryNotFound) {
+		t.Errorf("error = %v, want model.ErrRepositoryNotFound", err)
+	}
+}
+
+func TestCloseClosesComparer(t *testing.T) {
+	comparer := &fakeComparer{}
+	client := newTestClient(t, comparer)
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !comparer.closed {
+		t.Error("comparer not closed")
+	}
+}
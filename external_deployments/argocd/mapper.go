@@ -0,0 +1,29 @@
+package argocd
+
+import "github.com/kemonprogrammer/github-go-client/external_deployments/model"
+
+// toDeployment maps a sync history entry, each entry is a successful deployment
+func toDeployment(h RevisionHistory) *model.Deployment {
+	createdAt := h.DeployedAt
+	if h.DeployStartedAt != nil {
+		createdAt = *h.DeployStartedAt
+	}
+	return &model.Deployment{
+		ID:            h.ID,
+		SHA:           h.GetRevision(),
+		CreatedAt:     createdAt,
+		UpdatedAt:     h.DeployedAt,
+		SucceededAt:   h.DeployedAt,
+		ComparisonURL: "",
+		Added:         []*model.Commit{},
+		Removed:       []*model.Commit{},
+	}
+}
+
+func toDeployments(history []RevisionHistory) []*model.Deployment {
+	deployments := make([]*model.Deployment, len(history))
+	for i, h := range history {
+		deployments[i] = toDeployment(h)
+	}
+	return deployments
+}
@@ -0,0 +1,58 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type MockArgoCDClient struct {
+	namespace string
+}
+
+func NewMockAPI() API {
+	return &MockArgoCDClient{
+		namespace: "argocd",
+	}
+}
+
+func (ac *MockArgoCDClient) GetApplication(_ context.Context, name string) (*Application, error) {
+	time.Sleep(400 * time.Millisecond)
+
+	length := 10
+	if val, err := strconv.Atoi(os.Getenv("MOCK_DEPLOYMENTS_LENGTH")); err == nil {
+		length = val
+	}
+
+	// spread out sync timestamps randomly throughout the last 10 minutes
+	maxMs := (10 * time.Minute).Milliseconds()
+	history := make([]RevisionHistory, 0, length)
+	for i := range length {
+		deployedAt := time.Now().Add(-time.Duration(rand.Int64N(maxMs)) * time.Millisecond)
+		startedAt := deployedAt.Add(-30 * time.Second)
+		history = append(history, RevisionHistory{
+			Revision:        fmt.Sprintf("def456ghi%03d", i),
+			DeployedAt:      deployedAt,
+			DeployStartedAt: &startedAt,
+		})
+	}
+	// Argo CD lists the history oldest first with increasing IDs
+	slices.SortFunc(history, func(a, b RevisionHistory) int {
+		return a.DeployedAt.Compare(b.DeployedAt)
+	})
+	for i := range history {
+		history[i].ID = int64(i)
+	}
+
+	return &Application{
+		Metadata: ObjectMeta{Name: name, Namespace: ac.namespace},
+		Spec: ApplicationSpec{
+			Source: &ApplicationSource{RepoURL: "https://github.com/mock-owner/" + name + ".git", TargetRevision: "HEAD"},
+		},
+		Status: ApplicationStatus{History: history},
+	}, nil
+}
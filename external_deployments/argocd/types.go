@@ -0,0 +1,60 @@
+package argocd
+
+import "time"
+
+// Application of the Argo CD API, only the fields needed for the sync history,
+// see https://argo-cd.readthedocs.io/en/stable/developer-guide/api-docs/
+type Application struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Spec     ApplicationSpec   `json:"spec"`
+	Status   ApplicationStatus `json:"status"`
+}
+
+type ObjectMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type ApplicationSpec struct {
+	Source  *ApplicationSource  `json:"source,omitempty"`
+	Sources []ApplicationSource `json:"sources,omitempty"`
+}
+
+type ApplicationSource struct {
+	RepoURL        string `json:"repoURL"`
+	TargetRevision string `json:"targetRevision"`
+}
+
+type ApplicationStatus struct {
+	History []RevisionHistory `json:"history"`
+}
+
+// RevisionHistory is an entry of the sync history, DeployedAt is the time the sync finished
+type RevisionHistory struct {
+	ID              int64               `json:"id"`
+	Revision        string              `json:"revision"`
+	Revisions       []string            `json:"revisions,omitempty"`
+	DeployedAt      time.Time           `json:"deployedAt"`
+	DeployStartedAt *time.Time          `json:"deployStartedAt,omitempty"`
+	Source          *ApplicationSource  `json:"source,omitempty"`
+	Sources         []ApplicationSource `json:"sources,omitempty"`
+}
+
+// GetRevision returns the revision of the entry, the first one for applications with multiple sources
+func (h RevisionHistory) GetRevision() string {
+	if h.Revision == "" && len(h.Revisions) > 0 {
+		return h.Revisions[0]
+	}
+	return h.Revision
+}
+
+// RepoURL returns the repository of the application, the first one for applications with multiple sources
+func (a *Application) RepoURL() string {
+	if a.Spec.Source != nil {
+		return a.Spec.Source.RepoURL
+	}
+	if len(a.Spec.Sources) > 0 {
+		return a.Spec.Sources[0].RepoURL
+	}
+	return ""
+}
@@ -3,43 +3,37 @@ package external_deployments
 import (
 	"context"
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/kemonprogrammer/github-go-client/config"
-	"github.com/kemonprogrammer/github-go-client/external_deployments/github"
 	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
-	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
 )
 
+// DeploymentClient lists the deployments of the repository selected by the query.
+// Implementations are safe for concurrent use and serve any number of repositories.
 type DeploymentClient interface {
-	ListDeploymentsInRange(ctx context.Context, from, to time.Time) ([]*model.Deployment, error)
-	SetRepo(ctx context.Context, repo string) error
-	GetRepo() string
+	ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error)
 }
 
+// CacheInvalidator is implemented by clients caching the data of repositories
+type CacheInvalidator interface {
+	InvalidateCache(ctx context.Context, owner, repo string) error
+}
+
+// NewDeploymentClient creates the client of the registered provider conf.Provider,
+// routing the configured repositories with another provider to the client of that provider
 func NewDeploymentClient(conf *config.Config) (DeploymentClient, error) {
 	if !conf.Enabled {
 		return nil, fmt.Errorf("external deployments not enabled")
 	}
+	return newRoutingClient(conf)
+}
 
-	provider := conf.Provider
-	if provider == "github" {
-		owner := conf.Owner
-		if len(owner) == 0 {
-			return nil, fmt.Errorf("external_service.external_deployments.auth.username not set in config")
-		}
-
-		ghAPI, err := github.NewAPI(conf)
-		if err != nil {
-			return nil, err
-		}
-		if os.Getenv("TEST") == "true" {
-			log.Info("using mock GitHub client")
-			ghAPI = github.NewMockAPI()
-		}
-		return github.NewDeploymentClient(ghAPI)
+// newProviderClient creates the client of the registered provider conf.Provider
+func newProviderClient(conf *config.Config) (DeploymentClient, error) {
+	factory, err := lookupProvider(conf.Provider)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("external deployments provider %s not supported ", provider)
+	return factory(conf)
 }
@@ -0,0 +1,153 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+// duplicateWindow is how far apart deployments of the same commit from different sources may succeed
+// to be merged into one
+const duplicateWindow = time.Minute
+
+// Lister is implemented by the deployment clients of the providers
+type Lister interface {
+	ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error)
+}
+
+// Source is a provider queried by the composite client, Name tags its deployments
+type Source struct {
+	Name   string
+	Client Lister
+}
+
+// DeploymentClient fans queries out to several providers and merges their deployments, newest first.
+// Deployments of the same commit which succeeded within duplicateWindow are merged into the one of the
+// first source, tagged with the names of all of them. The commits of a deployment are the ones its
+// source compared to its previous deployment of that source.
+//
+// If some sources fail the deployments of the others are returned with a *model.PartialError.
+// It is safe for concurrent use.
+type DeploymentClient struct {
+	sources []Source
+}
+
+func NewDeploymentClient(sources []Source) (*DeploymentClient, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no sources provided")
+	}
+	for _, source := range sources {
+		if source.Client == nil {
+			return nil, fmt.Errorf("client of source %s cannot be nil", source.Name)
+		}
+	}
+	return &DeploymentClient{
+		sources: sources,
+	}, nil
+}
+
+// ListDeploymentsInRange lists the merged deployments of all sources in range [q.From, q.To]
+func (cdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	results := make([][]*model.Deployment, len(cdc.sources))
+	errs := make([]error, len(cdc.sources))
+
+	var wg sync.WaitGroup
+	for i, source := range cdc.sources {
+		wg.Go(func() {
+			deployments, err := source.Client.ListDeploymentsInRange(ctx, q)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", source.Name, err)
+				return
+			}
+			// the deployments may be cached by the source, they are tagged and merged as copies
+			tagged := make([]*model.Deployment, len(deployments))
+			for j, d := range deployments {
+				c := *d
+				c.Source = source.Name
+				tagged[j] = &c
+			}
+			results[i] = tagged
+		})
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == len(cdc.sources) {
+		return nil, errors.Join(failed...)
+	}
+
+	merged := merge(results)
+	if len(failed) > 0 {
+		for _, err := range failed {
+			log.Warnf("returning partial result, source %v", err)
+		}
+		return merged, &model.PartialError{Errors: failed}
+	}
+	return merged, nil
+}
+
+// merge returns the deployments of all sources newest first, deployments of the same commit
+// which succeeded within duplicateWindow are merged into the one of the earlier source
+func merge(results [][]*model.Deployment) []*model.Deployment {
+	var merged []*model.Deployment
+	for _, deployments := range results {
+		for _, d := range deployments {
+			i := slices.IndexFunc(merged, func(m *model.Deployment) bool {
+				return m.SHA == d.SHA && m.SucceededAt.Sub(d.SucceededAt).Abs() <= duplicateWindow
+			})
+			if i < 0 {
+				merged = append(merged, d)
+				continue
+			}
+			merged[i].Source += "," + d.Source
+		}
+	}
+
+	slices.SortStableFunc(merged, func(a, b *model.Deployment) int {
+		return b.SucceededAt.Compare(a.SucceededAt)
+	})
+	return merged
+}
+
+// InvalidateCache drops the cached data of owner/repo in all sources caching data
+func (cdc *DeploymentClient) InvalidateCache(ctx context.Context, owner, repo string) error {
+	var errs []error
+	for _, source := range cdc.sources {
+		invalidator, ok := source.Client.(interface {
+			InvalidateCache(ctx context.Context, owner, repo string) error
+		})
+		if !ok {
+			continue
+		}
+		if err := invalidator.InvalidateCache(ctx, owner, repo); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the sources holding resources
+func (cdc *DeploymentClient) Close() error {
+	var errs []error
+	for _, source := range cdc.sources {
+		if closer, ok := source.Client.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", source.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
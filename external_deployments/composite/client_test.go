@@ -0,0 +1,167 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+var day = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+// fakeLister returns the same deployments or error for every query
+type fakeLister struct {
+	deployments []*model.Deployment
+	err         error
+}
+
+func (f *fakeLister) ListDeploymentsInRange(context.Context, models.DeploymentsQuery) ([]*model.Deployment, error) {
+	return f.deployments, f.err
+}
+
+func deployment(id int64, sha string, succeededAt time.Duration) *model.Deployment {
+	return &model.Deployment{ID: id, SHA: sha, SucceededAt: day.Add(succeededAt)}
+}
+
+func newTestClient(t *testing.T, sources ...Source) *DeploymentClient {
+	t.Helper()
+	client, err := NewDeploymentClient(sources)
+	if err != nil {
+		t.Fatalf("NewDeploymentClient() error = %v", err)
+	}
+	return client
+}
+
+func list(client *DeploymentClient) ([]*model.Deployment, error) {
+	return client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{Owner: "acme", Repo: "shop", From: day, To: day.Add(24 * time.Hour)})
+}
+
+// tagged returns sha:source of the deployments
+func tagged(deployments []*model.Deployment) []string {
+	result := make([]string, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.SHA + ":" + d.Source
+	}
+	return result
+}
+
+func TestListDeploymentsInRangeMergesSources(t *testing.T) {
+	github := &fakeLister{deployments: []*model.Deployment{
+		deployment(1, "b", 12*time.Hour),
+		deployment(2, "a", 10*time.Hour),
+	}}
+	argocd := &fakeLister{deployments: []*model.Deployment{
+		deployment(11, "b", 12*time.Hour+5*time.Minute),
+		deployment(12, "c", 11*time.Hour),
+		deployment(13, "a", 10*time.Hour+30*time.Second),
+	}}
+	flux := &fakeLister{deployments: []*model.Deployment{
+		deployment(21, "a", 10*time.Hour-time.Minute),
+	}}
+	client := newTestClient(t, Source{Name: "github", Client: github}, Source{Name: "argocd", Client: argocd}, Source{Name: "flux", Client: flux})
+
+	got, err := list(client)
+	if err != nil {
+		t.Fatalf("ListDeploymentsInRange() error = %v", err)
+	}
+	want := []string{
+		// the same commit, but deployed minutes apart
+		"b:argocd",
+		"b:github",
+		"c:argocd",
+		// merged into the deployment of the first source
+		"a:github,argocd,flux",
+	}
+	if !slices.Equal(tagged(got), want) {
+		t.Errorf("deployments = %v, want %v", tagged(got), want)
+	}
+	if got[3].ID != 2 {
+		t.Errorf("merged deployment ID = %d, want 2 of the first source", got[3].ID)
+	}
+
+	// the deployments of the sources are left untouched
+	for _, source := range []*fakeLister{github, argocd, flux} {
+		for _, d := range source.deployments {
+			if d.Source != "" {
+				t.Errorf("source deployment %d tagged with %q", d.ID, d.Source)
+			}
+		}
+	}
+}
+
+func TestListDeploymentsInRangeConcurrently(t *testing.T) {
+	github := &fakeLister{deployments: []*model.Deployment{deployment(1, "a", 10*time.Hour)}}
+	argocd := &fakeLister{deployments: []*model.Deployment{deployment(11, "a", 10*time.Hour)}}
+	client := newTestClient(t, Source{Name: "github", Client: github}, Source{Name: "argocd", Client: argocd})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			got, err := list(client)
+			if err != nil {
+				t.Errorf("ListDeploymentsInRange() error = %v", err)
+				return
+			}
+			if want := []string{"a:github,argocd"}; !slices.Equal(tagged(got), want) {
+				t.Errorf("deployments = %v, want %v", tagged(got), want)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestListDeploymentsInRangeReturnsPartialResult(t *testing.T) {
+	errUnavailable := errors.New("argo cd unavailable")
+	client := newTestClient(t,
+		Source{Name: "github", Client: &fakeLister{deployments: []*model.Deployment{deployment(1, "a", 10*time.Hour)}}},
+		Source{Name: "argocd", Client: &fakeLister{err: errUnavailable}},
+	)
+
+	got, err := list(client)
+	if want := []string{"a:github"}; !slices.Equal(tagged(got), want) {
+		t.Errorf("deployments = %v, want %v", tagged(got), want)
+	}
+	var partialErr *model.PartialError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("error = %v, want *model.PartialError", err)
+	}
+	if len(partialErr.Errors) != 1 || partialErr.Errors[0].Error() != "argocd: argo cd unavailable" {
+		t.Errorf("Errors = %v, want the error of argocd", partialErr.Errors)
+	}
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("error = %v, want it to match the error of argocd", err)
+	}
+}
+
+func TestListDeploymentsInRangeAllSourcesFail(t *testing.T) {
+	client := newTestClient(t,
+		Source{Name: "github", Client: &fakeLister{err: model.ErrRepositoryNotFound}},
+		Source{Name: "argocd", Client: &fakeLister{err: errors.New("argo cd unavailable")}},
+	)
+
+	got, err := list(client)
+	if got != nil {
+		t.Errorf("deployments = %v, want nil", tagged(got))
+	}
+	if err == nil {
+		t.Fatal("error = nil, want the errors of all sources")
+	}
+	var partialErr *model.PartialError
+	if errors.As(err, &partialErr) {
+		t.Errorf("error = %v, want no partial result", err)
+	}
+	if !errors.Is(err, model.ErrRepositoryNotFound) {
+		t.Errorf("error = %v, want it to match model.ErrRepositoryNotFound", err)
+	}
+	for _, want := range []string{"github: ", "argocd: argo cd unavailable"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+// Settings are the provider settings file of the config
+type Settings struct {
+	Path string `json:"path"`
+}
+
+// DeploymentClient serves deployments from a JSON or NDJSON file in the shape the API responds with,
+// e.g. visualize/custom-deploys.json. The commits are taken from the file as they are.
+// Each deployment belongs to the repository of its repository field, owner/name or name of a repository
+// of the configured owner, or else of its GitHub API url. The file is reloaded once it changed.
+// It is safe for concurrent use.
+type DeploymentClient struct {
+	path  string
+	owner string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	size    int64
+	// repos maps owner/name, in lower case, to the successful deployments of the repository
+	// sorted by SucceededAt, newest first
+	repos map[string][]*model.Deployment
+}
+
+func NewDeploymentClient(conf *config.Config, settings Settings) (*DeploymentClient, error) {
+	if len(settings.Path) == 0 {
+		return nil, fmt.Errorf("no deployments file provided")
+	}
+	fdc := &DeploymentClient{
+		path:  settings.Path,
+		owner: conf.Owner,
+	}
+	if _, err := fdc.load(); err != nil {
+		return nil, err
+	}
+	return fdc, nil
+}
+
+// ListDeploymentsInRange lists the deployments of q.Owner/q.Repo in the file which succeeded in range (q.From, q.To).
+// If the file has no deployments of the repository the error matches model.ErrRepositoryNotFound.
+func (fdc *DeploymentClient) ListDeploymentsInRange(_ context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	repos, err := fdc.load()
+	if err != nil {
+		return nil, err
+	}
+	owner := q.Owner
+	if owner == "" {
+		owner = fdc.owner
+	}
+	successful, ok := repos[repoKey(owner, q.Repo)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no deployments of %s/%s in %s", model.ErrRepositoryNotFound, owner, q.Repo, fdc.path)
+	}
+
+	inRange, _ := model.SplitRange(successful, q.From, q.To)
+
+	deployments := make([]*model.Deployment, len(inRange))
+	for i, d := range inRange {
+		c := *d
+		deployments[i] = &c
+	}
+	return deployments, nil
+}
+
+// load returns the deployments of the file by repository, reading it again if its modification time or size
+// changed. If the changed file can't be read the previously loaded deployments are kept.
+func (fdc *DeploymentClient) load() (map[string][]*model.Deployment, error) {
+	info, err := os.Stat(fdc.path)
+	if err != nil {
+		return fdc.keepLoaded(fmt.Errorf("error while reading deployments file: %w", err))
+	}
+
+	fdc.mu.RLock()
+	unchanged := fdc.repos != nil && info.ModTime().Equal(fdc.modTime) && info.Size() == fdc.size
+	repos := fdc.repos
+	fdc.mu.RUnlock()
+	if unchanged {
+		return repos, nil
+	}
+
+	fdc.mu.Lock()
+	defer fdc.mu.Unlock()
+	// another query reloaded the file in the meantime
+	if fdc.repos != nil && info.ModTime().Equal(fdc.modTime) && info.Size() == fdc.size {
+		return fdc.repos, nil
+	}
+
+	data, err := os.ReadFile(fdc.path)
+	if err != nil {
+		return fdc.keepLoadedLocked(fmt.Errorf("error while reading deployments file: %w", err))
+	}
+	deployments, err := decodeDeployments(fdc.path, data)
+	if err != nil {
+		return fdc.keepLoadedLocked(err)
+	}
+
+	repos = make(map[string][]*model.Deployment)
+	count := 0
+	for _, fd := range deployments {
+		if fd == nil || fd.SucceededAt.IsZero() {
+			continue
+		}
+		if fd.Repository == "" {
+			return fdc.keepLoadedLocked(fmt.Errorf("deployment %d of %s has neither repository nor url", fd.ID, fdc.path))
+		}
+		owner, name, found := strings.Cut(fd.Repository, "/")
+		if !found {
+			owner, name = fdc.owner, fd.Repository
+		}
+		d := &fd.Deployment
+		if d.Added == nil {
+			d.Added = []*model.Commit{}
+		}
+		if d.Removed == nil {
+			d.Removed = []*model.Commit{}
+		}
+		key := repoKey(owner, name)
+		repos[key] = append(repos[key], d)
+		count++
+	}
+	for _, successful := range repos {
+		slices.SortStableFunc(successful, func(a, b *model.Deployment) int {
+			return b.SucceededAt.Compare(a.SucceededAt)
+		})
+	}
+
+	log.Debugf("loaded %d deployments of %d repositories from %s", count, len(repos), fdc.path)
+	fdc.repos = repos
+	fdc.modTime = info.ModTime()
+	fdc.size = info.Size()
+	return repos, nil
+}
+
+// repoKey returns the key of owner/name in the loaded deployments, GitHub names are case-insensitive
+func repoKey(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
+
+func (fdc *DeploymentClient) keepLoaded(err error) (map[string][]*model.Deployment, error) {
+	fdc.mu.RLock()
+	defer fdc.mu.RUnlock()
+	return fdc.keepLoadedLocked(err)
+}
+
+// keepLoadedLocked returns the previously loaded deployments and logs err, or returns err if none were loaded
+func (fdc *DeploymentClient) keepLoadedLocked(err error) (map[string][]*model.Deployment, error) {
+	if fdc.repos == nil {
+		return nil, err
+	}
+	log.Warnf("keeping previously loaded deployments: %v", err)
+	return fdc.repos, nil
+}
@@ -0,0 +1,163 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+const deploymentsJSON = `[
+  {"id": 1, "url": "https://api.github.com/repos/acme/shop/deployments/1", "sha": "a1", "succeeded_at": "2026-03-18T10:00:00Z", "added": null, "removed": null},
+  {"id": 2, "url": "https://api.github.com/repos/acme/shop/deployments/2", "sha": "a2", "succeeded_at": "2026-03-18T12:00:00Z"},
+  {"id": 3, "url": "https://api.github.com/repos/acme/shop/deployments/3", "sha": "a3", "succeeded_at": ""},
+  {"id": 4, "repository": "acme/cart", "sha": "b1", "succeeded_at": "2026-03-18T11:00:00Z"},
+  {"id": 5, "repository": "Billing", "sha": "c1", "succeeded_at": "2026-03-18T11:30:00Z"}
+]`
+
+const deploymentsNDJSON = `{"id": 1, "url": "https://api.github.com/repos/acme/shop/deployments/1", "sha": "a1", "succeeded_at": "2026-03-18T10:00:00Z"}
+
+{"id": 2, "url": "https://api.github.com/repos/acme/shop/deployments/2", "sha": "a2", "succeeded_at": "2026-03-18T12:00:00Z"}
+{"id": 4, "repository": "acme/cart", "sha": "b1", "succeeded_at": "2026-03-18T11:00:00Z"}
+{"id": 5, "repository": "Billing", "sha": "c1", "succeeded_at": "2026-03-18T11:30:00Z"}
+`
+
+var day = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestClient(t *testing.T, name, content string) (*DeploymentClient, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	writeFile(t, path, content)
+	client, err := NewDeploymentClient(&config.Config{Owner: "acme"}, Settings{Path: path})
+	if err != nil {
+		t.Fatalf("NewDeploymentClient() error = %v", err)
+	}
+	return client, path
+}
+
+func ids(deployments []*model.Deployment) []int64 {
+	result := make([]int64, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.ID
+	}
+	return result
+}
+
+func list(t *testing.T, client *DeploymentClient, owner, repo string, from, to time.Time) []int64 {
+	t.Helper()
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{Owner: owner, Repo: repo, From: from, To: to})
+	if err != nil {
+		t.Fatalf("ListDeploymentsInRange(%s/%s) error = %v", owner, repo, err)
+	}
+	return ids(deployments)
+}
+
+func TestListDeploymentsInRangeByRepository(t *testing.T) {
+	for name, content := range map[string]string{
+		"deployments.json":   deploymentsJSON,
+		"deployments.ndjson": deploymentsNDJSON,
+		// NDJSON is detected by the content as well
+		"deployments.txt": deploymentsNDJSON,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, _ := newTestClient(t, name, content)
+			from, to := day, day.Add(24*time.Hour)
+
+			tests := []struct {
+				owner, repo string
+				want        []int64
+			}{
+				{owner: "acme", repo: "shop", want: []int64{2, 1}},
+				{owner: "ACME", repo: "Cart", want: []int64{4}},
+				// repositories without owner belong to the configured owner, which queries without owner use
+				{owner: "", repo: "billing", want: []int64{5}},
+			}
+			for _, tt := range tests {
+				if got := list(t, client, tt.owner, tt.repo, from, to); !slices.Equal(got, tt.want) {
+					t.Errorf("deployments of %s/%s = %v, want %v", tt.owner, tt.repo, got, tt.want)
+				}
+			}
+
+			_, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{Owner: "other", Repo: "shop", From: from, To: to})
+			if !errors.Is(err, model.ErrRepositoryNotFound) {
+				t.Errorf("error of repository not in the file = %v, want model.ErrRepositoryNotFound", err)
+			}
+		})
+	}
+}
+
+func TestListDeploymentsInRangeFiltersBySuccessTime(t *testing.T) {
+	client, _ := newTestClient(t, "deployments.json", deploymentsJSON)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []int64
+	}{
+		{name: "both", from: day.Add(9 * time.Hour), to: day.Add(13 * time.Hour), want: []int64{2, 1}},
+		{name: "bounds excluded", from: day.Add(10 * time.Hour), to: day.Add(12 * time.Hour), want: []int64{}},
+		{name: "newest", from: day.Add(11 * time.Hour), to: day.Add(13 * time.Hour), want: []int64{2}},
+		{name: "none", from: day.Add(13 * time.Hour), to: day.Add(14 * time.Hour), want: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list(t, client, "acme", "shop", tt.from, tt.to); !slices.Equal(got, tt.want) {
+				t.Errorf("deployments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListDeploymentsInRangeReloadsChangedFile(t *testing.T) {
+	client, path := newTestClient(t, "deployments.json", deploymentsJSON)
+	from, to := day, day.Add(24*time.Hour)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same size, only the modification time tells the change
+	changed := []byte(deploymentsJSON)
+	changed[len(`[
+  {"id": `)] = '7'
+	writeFile(t, path, string(changed))
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(t, client, "acme", "shop", from, to), []int64{2, 7}; !slices.Equal(got, want) {
+		t.Errorf("deployments after changing the modification time = %v, want %v", got, want)
+	}
+
+	writeFile(t, path, `[{"id": 9, "repository": "shop", "sha": "a9", "succeeded_at": "2026-03-18T15:00:00Z"}]`)
+	if got, want := list(t, client, "acme", "shop", from, to), []int64{9}; !slices.Equal(got, want) {
+		t.Errorf("deployments after changing the size = %v, want %v", got, want)
+	}
+
+	// an invalid file keeps the deployments loaded before
+	writeFile(t, path, `[{"id": 10, "sha": "a10", "succeeded_at": "2026-03-18T16:00:00Z"}`)
+	if got, want := list(t, client, "acme", "shop", from, to), []int64{9}; !slices.Equal(got, want) {
+		t.Errorf("deployments after writing an invalid file = %v, want %v", got, want)
+	}
+}
+
+func TestNewDeploymentClientRequiresRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deployments.json")
+	writeFile(t, path, `[{"id": 1, "sha": "a1", "succeeded_at": "2026-03-18T10:00:00Z"}]`)
+	if _, err := NewDeploymentClient(&config.Config{}, Settings{Path: path}); err == nil {
+		t.Error("NewDeploymentClient() error = nil, want deployments without repository rejected")
+	}
+}
@@ -0,0 +1,87 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// fileDeployment is a deployment of the file and the repository it belongs to
+type fileDeployment struct {
+	model.Deployment
+	// Repository is owner/name or name, taken from the repository field or else the url of the deployment
+	Repository string
+}
+
+func (d *fileDeployment) UnmarshalJSON(data []byte) error {
+	if err := d.Deployment.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var raw struct {
+		Repository string `json:"repository"`
+		URL        string `json:"url"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	d.Repository = raw.Repository
+	if d.Repository == "" {
+		d.Repository = repoOfURL(raw.URL)
+	}
+	return nil
+}
+
+// repoOfURL returns owner/name of a deployment's API URL like
+// https://api.github.com/repos/owner/name/deployments/1, or "" for other URLs
+func repoOfURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "repos" && i+2 < len(segments) && segments[i+1] != "" && segments[i+2] != "" {
+			return segments[i+1] + "/" + segments[i+2]
+		}
+	}
+	return ""
+}
+
+// decodeDeployments parses a JSON array of deployments, or one deployment per line (NDJSON)
+// for files ending in .ndjson or .jsonl or not starting with "["
+func decodeDeployments(name string, data []byte) ([]*fileDeployment, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	trimmed := bytes.TrimSpace(data)
+	if ext != ".ndjson" && ext != ".jsonl" && bytes.HasPrefix(trimmed, []byte("[")) {
+		var deployments []*fileDeployment
+		if err := json.Unmarshal(trimmed, &deployments); err != nil {
+			return nil, fmt.Errorf("error while decoding %s: %w", name, err)
+		}
+		return deployments, nil
+	}
+
+	var deployments []*fileDeployment
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var d fileDeployment
+		if err := json.Unmarshal(text, &d); err != nil {
+			return nil, fmt.Errorf("error while decoding %s line %d: %w", name, line, err)
+		}
+		deployments = append(deployments, &d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading %s: %w", name, err)
+	}
+	return deployments, nil
+}
@@ -0,0 +1,120 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+var (
+	kustomizationsResource  = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	helmReleasesResource    = schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"}
+	gitRepositoriesResource = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}
+)
+
+// API mock for testing
+type API interface {
+	GetKustomization(ctx context.Context, namespace, name string) (*Kustomization, error)
+	GetHelmRelease(ctx context.Context, namespace, name string) (*HelmRelease, error)
+	GetGitRepository(ctx context.Context, namespace, name string) (*GitRepository, error)
+}
+
+// Settings are the provider settings flux of the config
+type Settings struct {
+	// KubeConfig is the kubeconfig file of the cluster running Flux, empty for the one of the config
+	KubeConfig string `json:"kubeConfig"`
+	// Namespace is the namespace of the Flux resources, empty for the namespace of the workload
+	Namespace string `json:"namespace"`
+}
+
+type Client struct {
+	client dynamic.Interface
+}
+
+// NewAPI connects to the cluster of settings.KubeConfig or conf.KubeConfig, or the cluster it runs in if no
+// kubeconfig is set
+func NewAPI(conf *config.Config, settings Settings) (API, error) {
+	kubeConfig := settings.KubeConfig
+	if len(kubeConfig) == 0 {
+		kubeConfig = conf.KubeConfig
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error while loading kubernetes config: %w", err)
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating kubernetes client: %w", err)
+	}
+	return NewFluxClient(client)
+}
+
+// NewFluxClient reads the Flux resources with client, e.g. a fake dynamic client in tests
+func NewFluxClient(client dynamic.Interface) (API, error) {
+	if client == nil {
+		return nil, fmt.Errorf("kubernetes client cannot be nil")
+	}
+	return &Client{
+		client: client,
+	}, nil
+}
+
+func (fc *Client) GetKustomization(ctx context.Context, namespace, name string) (*Kustomization, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getKustomization took %v\n", time.Since(start))
+	}()
+
+	var ks Kustomization
+	if err := fc.get(ctx, kustomizationsResource, namespace, name, &ks); err != nil {
+		return nil, err
+	}
+	return &ks, nil
+}
+
+func (fc *Client) GetHelmRelease(ctx context.Context, namespace, name string) (*HelmRelease, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getHelmRelease took %v\n", time.Since(start))
+	}()
+
+	var hr HelmRelease
+	if err := fc.get(ctx, helmReleasesResource, namespace, name, &hr); err != nil {
+		return nil, err
+	}
+	return &hr, nil
+}
+
+func (fc *Client) GetGitRepository(ctx context.Context, namespace, name string) (*GitRepository, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getGitRepository took %v\n", time.Since(start))
+	}()
+
+	var repo GitRepository
+	if err := fc.get(ctx, gitRepositoriesResource, namespace, name, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// get reads the resource into obj, errors of the kubernetes API are returned unwrapped to be checked with apierrors
+func (fc *Client) get(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, obj any) error {
+	u, err := fc.client.Resource(resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj); err != nil {
+		return fmt.Errorf("error while converting %s %s/%s: %w", resource.Resource, namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+// CommitComparer populates deployments with the commits added and removed compared to the next older one,
+// github.DeploymentClient implements it
+type CommitComparer interface {
+	PopulateWithCommits(ctx context.Context, owner, repo string, deployments []*model.Deployment) ([]*model.Deployment, error)
+}
+
+// DeploymentClient reads the reconciliation history of the Flux Kustomization or HelmRelease named like
+// the workload. The commits are compared in the repository of its GitRepository source.
+// It is safe for concurrent use.
+type DeploymentClient struct {
+	api       API
+	comparer  CommitComparer
+	namespace string
+}
+
+// NewDeploymentClient looks up the Flux resources in settings.Namespace, or the namespace of the workload if not set
+func NewDeploymentClient(api API, comparer CommitComparer, settings Settings) (*DeploymentClient, error) {
+	if api == nil {
+		return nil, fmt.Errorf("api cannot be nil")
+	}
+	if comparer == nil {
+		return nil, fmt.Errorf("comparer cannot be nil")
+	}
+	return &DeploymentClient{
+		api:       api,
+		comparer:  comparer,
+		namespace: settings.Namespace,
+	}, nil
+}
+
+// Close closes the comparer if it holds resources, e.g. the store of the GitHub client
+func (fdc *DeploymentClient) Close() error {
+	if closer, ok := fdc.comparer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ListDeploymentsInRange lists the reconciliations of the Kustomization, or HelmRelease if there is none,
+// named like q.Workload which were first applied in range [q.From, q.To]
+func (fdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	namespace := fdc.namespace
+	if namespace == "" {
+		namespace = q.Namespace
+	}
+
+	successful, source, err := fdc.loadHistory(ctx, namespace, q.Workload)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repo := q.Owner, q.Repo
+	if o, r, ok := fdc.sourceRepository(ctx, source); ok {
+		owner, repo = o, r
+	}
+
+	slices.SortFunc(successful, func(a, b *model.Deployment) int {
+		return b.SucceededAt.Compare(a.SucceededAt)
+	})
+
+	inRange, oneBefore := model.SplitRange(successful, q.From, q.To)
+
+	if oneBefore != nil {
+		inRange = append(inRange, oneBefore)
+	}
+
+	populated, err := fdc.comparer.PopulateWithCommits(ctx, owner, repo, inRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove one before
+	if oneBefore != nil {
+		populated = populated[:len(populated)-1]
+	}
+	return populated, nil
+}
+
+// loadHistory returns the successful deployments of the Kustomization or HelmRelease and its source
+func (fdc *DeploymentClient) loadHistory(ctx context.Context, namespace, name string) ([]*model.Deployment, SourceReference, error) {
+	ks, err := fdc.api.GetKustomization(ctx, namespace, name)
+	if err == nil {
+		return kustomizationToDeployments(ks), withNamespace(ks.Spec.SourceRef, namespace), nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, SourceReference{}, fmt.Errorf("error while getting flux kustomization %s/%s: %w", namespace, name, err)
+	}
+
+	hr, err := fdc.api.GetHelmRelease(ctx, namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, SourceReference{}, fmt.Errorf("%w: flux kustomization or helm release %s/%s", model.ErrRepositoryNotFound, namespace, name)
+		}
+		return nil, SourceReference{}, fmt.Errorf("error while getting flux helm release %s/%s: %w", namespace, name, err)
+	}
+
+	var source SourceReference
+	if hr.Spec.Chart != nil {
+		source = withNamespace(hr.Spec.Chart.Spec.SourceRef, namespace)
+	}
+	return helmReleaseToDeployments(hr), source, nil
+}
+
+// sourceRepository returns owner and name of the repository of a GitRepository source,
+// ok is false for other sources
+func (fdc *DeploymentClient) sourceRepository(ctx context.Context, source SourceReference) (string, string, bool) {
+	if source.Kind != "GitRepository" {
+		return "", "", false
+	}
+	gitRepo, err := fdc.api.GetGitRepository(ctx, source.Namespace, source.Name)
+	if err != nil {
+		log.Warnf("couldn't get flux git repository %s/%s, using the repository of the query: %v", source.Namespace, source.Name, err)
+		return "", "", false
+	}
+	return model.ParseRepoURL(gitRepo.Spec.URL)
+}
+
+func withNamespace(ref SourceReference, namespace string) SourceReference {
+	if ref.Namespace == "" {
+		ref.Namespace = namespace
+	}
+	return ref
+}
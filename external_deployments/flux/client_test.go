@@ -0,0 +1,185 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+var baseTime = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+// fakeComparer records the compared deployments
+type fakeComparer struct {
+	owner, repo string
+	compared    []string
+	closed      bool
+}
+
+func (f *fakeComparer) PopulateWithCommits(_ context.Context, owner, repo string, deployments []*model.Deployment) ([]*model.Deployment, error) {
+	f.owner, f.repo = owner, repo
+	for _, d := range deployments {
+		f.compared = append(f.compared, d.SHA)
+	}
+	return deployments, nil
+}
+
+func (f *fakeComparer) Close() error {
+	f.closed = true
+	return nil
+}
+
+func object(gvr schema.GroupVersionResource, kind, namespace, name string, fields map[string]any) *unstructured.Unstructured {
+	content := map[string]any{
+		"apiVersion": gvr.GroupVersion().String(),
+		"kind":       kind,
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+	}
+	for k, v := range fields {
+		content[k] = v
+	}
+	return &unstructured.Unstructured{Object: content}
+}
+
+func at(hours int) string {
+	return baseTime.Add(time.Duration(hours) * time.Hour).Format(time.RFC3339)
+}
+
+// newTestClient serves the Kustomization shop from the GitRepository shop and the HelmRelease cart
+// from a HelmRepository, both in the namespace apps
+func newTestClient(t *testing.T, comparer CommitComparer) *DeploymentClient {
+	t.Helper()
+	objects := []runtime.Object{
+		object(kustomizationsResource, "Kustomization", "apps", "shop", map[string]any{
+			"spec": map[string]any{"sourceRef": map[string]any{"kind": "GitRepository", "name": "shop"}},
+			"status": map[string]any{"history": []any{
+				map[string]any{"digest": "d3", "firstReconciled": at(3), "lastReconciled": at(4), "lastReconciledStatus": "ReconciliationSucceeded", "totalReconciliations": int64(2), "metadata": map[string]any{"revision": "main@sha1:c3"}},
+				map[string]any{"digest": "d2", "firstReconciled": at(2), "lastReconciled": at(2), "lastReconciledStatus": "ReconciliationFailed", "totalReconciliations": int64(1), "metadata": map[string]any{"revision": "main@sha1:c2"}},
+				map[string]any{"digest": "d1", "firstReconciled": at(1), "lastReconciled": at(1), "lastReconciledStatus": "ReconciliationSucceeded", "totalReconciliations": int64(1), "metadata": map[string]any{"revision": "main@sha1:c1"}},
+				map[string]any{"digest": "d0", "firstReconciled": at(0), "lastReconciled": at(0), "lastReconciledStatus": "ReconciliationSucceeded", "totalReconciliations": int64(1), "metadata": map[string]any{"revision": "main@sha1:c0"}},
+			}},
+		}),
+		object(gitRepositoriesResource, "GitRepository", "apps", "shop", map[string]any{
+			"spec": map[string]any{"url": "https://github.com/acme/storefront"},
+		}),
+		object(helmReleasesResource, "HelmRelease", "apps", "cart", map[string]any{
+			"spec": map[string]any{"chart": map[string]any{"spec": map[string]any{
+				"chart":     "cart",
+				"sourceRef": map[string]any{"kind": "HelmRepository", "name": "charts"},
+			}}},
+			"status": map[string]any{"history": []any{
+				map[string]any{"name": "cart", "namespace": "apps", "version": int64(2), "chartName": "cart", "chartVersion": "1.1.0+h2", "lastDeployed": at(2), "status": "deployed"},
+				map[string]any{"name": "cart", "namespace": "apps", "version": int64(1), "chartName": "cart", "chartVersion": "1.0.0+h1", "lastDeployed": at(1), "status": "superseded"},
+			}},
+		}),
+	}
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		kustomizationsResource:  "KustomizationList",
+		helmReleasesResource:    "HelmReleaseList",
+		gitRepositoriesResource: "GitRepositoryList",
+	}, objects...)
+
+	api, err := NewFluxClient(dynamicClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewDeploymentClient(api, comparer, Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func shas(deployments []*model.Deployment) []string {
+	result := make([]string, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.SHA
+	}
+	return result
+}
+
+func TestListDeploymentsInRangeOfKustomization(t *testing.T) {
+	comparer := &fakeComparer{}
+	client := newTestClient(t, comparer)
+
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Owner:     "o",
+		Repo:      "shop",
+		Namespace: "apps",
+		Workload:  "shop",
+		From:      baseTime.Add(30 * time.Minute),
+		To:        baseTime.Add(5 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := shas(deployments), []string{"c3", "c1"}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+	if comparer.owner != "acme" || comparer.repo != "storefront" {
+		t.Errorf("compared in %s/%s, want the repository of the GitRepository acme/storefront", comparer.owner, comparer.repo)
+	}
+	if want := []string{"c3", "c1", "c0"}; !slices.Equal(comparer.compared, want) {
+		t.Errorf("compared %v, want %v", comparer.compared, want)
+	}
+}
+
+func TestListDeploymentsInRangeOfHelmRelease(t *testing.T) {
+	comparer := &fakeComparer{}
+	client := newTestClient(t, comparer)
+
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Owner:     "o",
+		Repo:      "cart",
+		Namespace: "apps",
+		Workload:  "cart",
+		From:      baseTime,
+		To:        baseTime.Add(3 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := shas(deployments), []string{"h2", "h1"}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+	// a HelmRepository has no commits, the repository of the query is compared
+	if comparer.owner != "o" || comparer.repo != "cart" {
+		t.Errorf("compared in %s/%s, want o/cart", comparer.owner, comparer.repo)
+	}
+}
+
+func TestListDeploymentsInRangeWithoutFluxResource(t *testing.T) {
+	client := newTestClient(t, &fakeComparer{})
+
+	_, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Namespace: "apps",
+		Workload:  "unknown",
+		From:      baseTime,
+		To:        baseTime.Add(time.Hour),
+	})
+	if !errors.Is(err, model.ErrRepositoryNotFound) {
+		t.Errorf("error = %v, want model.ErrRepositoryNotFound", err)
+	}
+}
+
+func TestCloseClosesComparer(t *testing.T) {
+	comparer := &fakeComparer{}
+	client := newTestClient(t, comparer)
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !comparer.closed {
+		t.Error("comparer not closed")
+	}
+}
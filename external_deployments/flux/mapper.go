@@ -0,0 +1,104 @@
+package flux
+
+import (
+	"strings"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+const reconciliationSucceeded = "ReconciliationSucceeded"
+
+// kustomizationToDeployments maps the successful entries of the history, each is a deployment of the
+// revision first reconciled at FirstReconciled. Kustomizations without history, applied by Flux before
+// the history was introduced, yield their last applied revision only.
+func kustomizationToDeployments(ks *Kustomization) []*model.Deployment {
+	if len(ks.Status.History) == 0 {
+		ready := readyCondition(ks.Status.Conditions)
+		if ks.Status.LastAppliedRevision == "" || ready == nil {
+			return []*model.Deployment{}
+		}
+		return []*model.Deployment{
+			newDeployment(1, revisionSHA(ks.Status.LastAppliedRevision), ready),
+		}
+	}
+
+	deployments := make([]*model.Deployment, 0, len(ks.Status.History))
+	for i, s := range ks.Status.History {
+		revision := s.Metadata["revision"]
+		if s.LastReconciledStatus != reconciliationSucceeded || revision == "" {
+			continue
+		}
+		deployments = append(deployments, &model.Deployment{
+			// the history has no IDs, number the entries oldest first
+			ID:            int64(len(ks.Status.History) - i),
+			SHA:           revisionSHA(revision),
+			CreatedAt:     s.FirstReconciled,
+			UpdatedAt:     s.LastReconciled,
+			SucceededAt:   s.FirstReconciled,
+			ComparisonURL: "",
+			Added:         []*model.Commit{},
+			Removed:       []*model.Commit{},
+		})
+	}
+	return deployments
+}
+
+// helmReleaseToDeployments maps the deployed and superseded releases of the history,
+// releases of charts without a source revision are skipped
+func helmReleaseToDeployments(hr *HelmRelease) []*model.Deployment {
+	deployments := make([]*model.Deployment, 0, len(hr.Status.History))
+	for _, s := range hr.Status.History {
+		_, revision, found := strings.Cut(s.ChartVersion, "+")
+		if (s.Status != "deployed" && s.Status != "superseded") || !found {
+			continue
+		}
+		deployments = append(deployments, &model.Deployment{
+			ID:            s.Version,
+			SHA:           revision,
+			CreatedAt:     s.LastDeployed,
+			UpdatedAt:     s.LastDeployed,
+			SucceededAt:   s.LastDeployed,
+			ComparisonURL: "",
+			Added:         []*model.Commit{},
+			Removed:       []*model.Commit{},
+		})
+	}
+	return deployments
+}
+
+func newDeployment(id int64, sha string, ready *Condition) *model.Deployment {
+	return &model.Deployment{
+		ID:            id,
+		SHA:           sha,
+		CreatedAt:     ready.LastTransitionTime,
+		UpdatedAt:     ready.LastTransitionTime,
+		SucceededAt:   ready.LastTransitionTime,
+		ComparisonURL: "",
+		Added:         []*model.Commit{},
+		Removed:       []*model.Commit{},
+	}
+}
+
+// readyCondition returns the Ready condition if it is true
+func readyCondition(conditions []Condition) *Condition {
+	for i, c := range conditions {
+		if c.Type == "Ready" && c.Status == "True" {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// revisionSHA returns the commit of a Flux revision like main@sha1:8d2c3c1e, or main/8d2c3c1e before Flux v2.0
+func revisionSHA(revision string) string {
+	if _, digest, found := strings.Cut(revision, "@"); found {
+		revision = digest
+	}
+	if _, sha, found := strings.Cut(revision, ":"); found {
+		return sha
+	}
+	if i := strings.LastIndex(revision, "/"); i >= 0 {
+		return revision[i+1:]
+	}
+	return revision
+}
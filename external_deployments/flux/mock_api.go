@@ -0,0 +1,70 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type MockFluxClient struct {
+	repoURL string
+}
+
+func NewMockAPI() API {
+	return &MockFluxClient{
+		repoURL: "https://github.com/mock-owner/mock-repo",
+	}
+}
+
+func (fc *MockFluxClient) GetKustomization(_ context.Context, namespace, name string) (*Kustomization, error) {
+	time.Sleep(200 * time.Millisecond)
+
+	length := 10
+	if val, err := strconv.Atoi(os.Getenv("MOCK_DEPLOYMENTS_LENGTH")); err == nil {
+		length = val
+	}
+
+	// spread out reconciliations randomly throughout the last 10 minutes
+	maxMs := (10 * time.Minute).Milliseconds()
+	history := make([]Snapshot, 0, length)
+	for i := range length {
+		firstReconciled := time.Now().Add(-time.Duration(rand.Int64N(maxMs)) * time.Millisecond)
+		history = append(history, Snapshot{
+			Digest:               fmt.Sprintf("sha256:%064d", i),
+			FirstReconciled:      firstReconciled,
+			LastReconciled:       firstReconciled.Add(time.Minute),
+			LastReconciledStatus: reconciliationSucceeded,
+			TotalReconciliations: 1,
+			Metadata:             map[string]string{"revision": fmt.Sprintf("main@sha1:def456ghi%03d", i)},
+		})
+	}
+	// Flux lists the history newest first
+	slices.SortFunc(history, func(a, b Snapshot) int {
+		return b.FirstReconciled.Compare(a.FirstReconciled)
+	})
+
+	return &Kustomization{
+		Metadata: ObjectMeta{Name: name, Namespace: namespace},
+		Spec:     KustomizationSpec{SourceRef: SourceReference{Kind: "GitRepository", Name: name}},
+		Status:   KustomizationStatus{History: history},
+	}, nil
+}
+
+func (fc *MockFluxClient) GetHelmRelease(_ context.Context, _, name string) (*HelmRelease, error) {
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: helmReleasesResource.Group, Resource: helmReleasesResource.Resource}, name)
+}
+
+func (fc *MockFluxClient) GetGitRepository(_ context.Context, namespace, name string) (*GitRepository, error) {
+	time.Sleep(100 * time.Millisecond)
+	return &GitRepository{
+		Metadata: ObjectMeta{Name: name, Namespace: namespace},
+		Spec:     GitRepositorySpec{URL: fc.repoURL},
+	}, nil
+}
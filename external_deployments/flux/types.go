@@ -0,0 +1,98 @@
+package flux
+
+import "time"
+
+// Kustomization of the Flux kustomize controller, only the fields needed for the reconciliation history,
+// see https://fluxcd.io/flux/components/kustomize/kustomizations/
+type Kustomization struct {
+	Metadata ObjectMeta          `json:"metadata"`
+	Spec     KustomizationSpec   `json:"spec"`
+	Status   KustomizationStatus `json:"status"`
+}
+
+type ObjectMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type KustomizationSpec struct {
+	SourceRef SourceReference `json:"sourceRef"`
+}
+
+// SourceReference points to the source of a Kustomization or chart, Namespace defaults to the one of the referrer
+type SourceReference struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type KustomizationStatus struct {
+	LastAppliedRevision string      `json:"lastAppliedRevision,omitempty"`
+	Conditions          []Condition `json:"conditions,omitempty"`
+	History             []Snapshot  `json:"history,omitempty"`
+}
+
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// Snapshot is an entry of the Kustomization history, newest first. Metadata holds the source revision.
+type Snapshot struct {
+	Digest               string            `json:"digest"`
+	FirstReconciled      time.Time         `json:"firstReconciled"`
+	LastReconciled       time.Time         `json:"lastReconciled"`
+	LastReconciledStatus string            `json:"lastReconciledStatus"`
+	TotalReconciliations int64             `json:"totalReconciliations"`
+	Metadata             map[string]string `json:"metadata,omitempty"`
+}
+
+// HelmRelease of the Flux helm controller, only the fields needed for the release history,
+// see https://fluxcd.io/flux/components/helm/helmreleases/
+type HelmRelease struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Spec     HelmReleaseSpec   `json:"spec"`
+	Status   HelmReleaseStatus `json:"status"`
+}
+
+type HelmReleaseSpec struct {
+	Chart *HelmChartTemplate `json:"chart,omitempty"`
+}
+
+type HelmChartTemplate struct {
+	Spec HelmChartTemplateSpec `json:"spec"`
+}
+
+type HelmChartTemplateSpec struct {
+	Chart     string          `json:"chart"`
+	SourceRef SourceReference `json:"sourceRef"`
+}
+
+type HelmReleaseStatus struct {
+	History []HelmSnapshot `json:"history,omitempty"`
+}
+
+// HelmSnapshot is an entry of the HelmRelease history, newest first. Charts from a GitRepository
+// carry the source revision as build metadata of ChartVersion, e.g. 1.2.0+8d2c3c1e6f5a.
+type HelmSnapshot struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Version      int64     `json:"version"`
+	ChartName    string    `json:"chartName"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion,omitempty"`
+	LastDeployed time.Time `json:"lastDeployed"`
+	Status       string    `json:"status"`
+}
+
+// GitRepository of the Flux source controller, only the fields needed to find the repository
+type GitRepository struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Spec     GitRepositorySpec `json:"spec"`
+}
+
+type GitRepositorySpec struct {
+	URL string `json:"url"`
+}
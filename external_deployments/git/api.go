@@ -0,0 +1,158 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+// API mock for testing
+type API interface {
+	ListTags(ctx context.Context, dir string) ([]*Ref, error)
+	ListNotes(ctx context.Context, dir, notesRef string) ([]*Note, error)
+	// Log lists the commits reachable from head but not from base, newest first
+	Log(ctx context.Context, dir, base, head string) ([]*Commit, error)
+}
+
+// Client runs the git binary on local repositories, no remote is contacted
+type Client struct {
+	gitPath string
+}
+
+func NewAPI() (API, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, fmt.Errorf("git not found: %w", err)
+	}
+	return &Client{
+		gitPath: gitPath,
+	}, nil
+}
+
+func (gc *Client) ListTags(ctx context.Context, dir string) ([]*Ref, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("listTags took %v\n", time.Since(start))
+	}()
+
+	out, err := gc.run(ctx, dir, nil, "for-each-ref",
+		"--format=%(refname:strip=2)%00%(objectname)%00%(*objectname)%00%(creatordate:iso-strict)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []*Ref
+	for _, line := range lines(out) {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse date of tag %s: %w", fields[0], err)
+		}
+		// annotated tags point to the tag object, the commit is the peeled object
+		sha := fields[1]
+		if fields[2] != "" {
+			sha = fields[2]
+		}
+		refs = append(refs, &Ref{Name: fields[0], SHA: sha, Date: date})
+	}
+	return refs, nil
+}
+
+func (gc *Client) ListNotes(ctx context.Context, dir, notesRef string) ([]*Note, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("listNotes took %v\n", time.Since(start))
+	}()
+
+	out, err := gc.run(ctx, dir, nil, "notes", "--ref="+notesRef, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	// each line is "<note object> <annotated commit>"
+	var commits bytes.Buffer
+	for _, line := range lines(out) {
+		_, commit, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("unexpected notes list output %q", line)
+		}
+		commits.WriteString(commit + "\n")
+	}
+	if commits.Len() == 0 {
+		return nil, nil
+	}
+
+	out, err = gc.run(ctx, dir, &commits, "log", "--no-walk=unsorted", "--stdin", "--notes="+notesRef,
+		"--format=%H%x00%cI%x00%N%x1e")
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []*Note
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x00", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected log output %q", record)
+		}
+		date, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse date of commit %s: %w", fields[0], err)
+		}
+		notes = append(notes, &Note{SHA: fields[0], Message: fields[2], CommitDate: date})
+	}
+	return notes, nil
+}
+
+func (gc *Client) Log(ctx context.Context, dir, base, head string) ([]*Commit, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("log took %v\n", time.Since(start))
+	}()
+
+	out, err := gc.run(ctx, dir, nil, "log", "--format=%H%x00%s", base+".."+head, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*Commit
+	for _, line := range lines(out) {
+		sha, subject, _ := strings.Cut(line, "\x00")
+		commits = append(commits, &Commit{SHA: sha, Subject: subject})
+	}
+	return commits, nil
+}
+
+// run runs git in dir and returns its output, stdin is optional
+func (gc *Client) run(ctx context.Context, dir string, stdin *bytes.Buffer, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, gc.gitPath, append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func lines(out string) []string {
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
@@ -0,0 +1,209 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+const defaultMaxConcurrency = 8
+
+// DeploymentClient reads deployments from local clones without any forge API. Tags, or the lines of git
+// notes if a notes ref is configured, matching the pattern are deployments. Commits are compared by
+// walking the local commit graph. It is safe for concurrent use.
+// Settings are the provider settings git of the config
+type Settings struct {
+	RepoPath       string `json:"repoPath"`
+	DeployPattern  string `json:"deployPattern"`
+	DeployNotesRef string `json:"deployNotesRef"`
+}
+
+type DeploymentClient struct {
+	api            API
+	repoPath       string
+	pattern        string
+	env            string
+	notesRef       string
+	maxConcurrency int
+}
+
+// NewDeploymentClient reads the clone at settings.RepoPath, or the clones below it named <owner>/<repo> or <repo>.
+// The pattern defaults to deploy/<environment>/*, with the environment of the query if it has one.
+func NewDeploymentClient(api API, conf *config.Config, settings Settings) (*DeploymentClient, error) {
+	if api == nil {
+		return nil, fmt.Errorf("api cannot be nil")
+	}
+	if len(settings.RepoPath) == 0 {
+		return nil, fmt.Errorf("no local repository path provided")
+	}
+	if _, err := path.Match(settings.DeployPattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid deploy pattern %s: %w", settings.DeployPattern, err)
+	}
+	maxConcurrency := conf.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	return &DeploymentClient{
+		api:            api,
+		repoPath:       settings.RepoPath,
+		pattern:        settings.DeployPattern,
+		env:            conf.Env,
+		notesRef:       settings.DeployNotesRef,
+		maxConcurrency: maxConcurrency,
+	}, nil
+}
+
+// ListDeploymentsInRange lists the deployments of q.Owner/q.Repo which happened in range [q.From, q.To]
+func (gdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	dir, err := gdc.repoDir(q.Owner, q.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	successful, err := gdc.listDeployments(ctx, dir, gdc.patternOf(q.Environment))
+	if err != nil {
+		return nil, err
+	}
+
+	inRange, oneBefore := model.SplitRange(successful, q.From, q.To)
+
+	if oneBefore != nil {
+		inRange = append(inRange, oneBefore)
+	}
+
+	populated, err := gdc.populateWithCommits(ctx, dir, inRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove one before
+	if oneBefore != nil {
+		populated = populated[:len(populated)-1]
+	}
+	return populated, nil
+}
+
+// repoDir returns the clone of the repository, repoPath itself if it is a clone.
+// owner and repo come from the query, names which are not a single path element are rejected
+// so the clone is never looked up outside repoPath.
+func (gdc *DeploymentClient) repoDir(owner, repo string) (string, error) {
+	if !isPathElement(owner) || !isPathElement(repo) {
+		return "", fmt.Errorf("%w: invalid repository name %q/%q", model.ErrRepositoryNotFound, owner, repo)
+	}
+	candidates := []string{gdc.repoPath}
+	if repo != "" {
+		candidates = append(candidates, filepath.Join(gdc.repoPath, owner, repo), filepath.Join(gdc.repoPath, repo))
+	}
+	for _, dir := range candidates {
+		if isRepository(dir) {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no clone of %s/%s in %s", model.ErrRepositoryNotFound, owner, repo, gdc.repoPath)
+}
+
+// isPathElement reports whether name is empty or a single element of a path below a directory, e.g. not .. or a/b
+func isPathElement(name string) bool {
+	return name == "" || (name != "." && filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`))
+}
+
+// isRepository reports whether dir is a clone or a bare repository
+func isRepository(dir string) bool {
+	for _, name := range []string{".git", "HEAD"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// patternOf returns the configured pattern, or deploy/<environment>/* with the configured environment if environment is empty
+func (gdc *DeploymentClient) patternOf(environment string) string {
+	if gdc.pattern != "" {
+		return gdc.pattern
+	}
+	if environment == "" {
+		environment = gdc.env
+	}
+	return path.Join("deploy", environment, "*")
+}
+
+// listDeployments lists all deployments of the repository matching pattern, newest first.
+// Deployments have no IDs in git, they are numbered oldest first.
+func (gdc *DeploymentClient) listDeployments(ctx context.Context, dir, pattern string) ([]*model.Deployment, error) {
+	var deployments []*model.Deployment
+	if gdc.notesRef != "" {
+		notes, err := gdc.api.ListNotes(ctx, dir, gdc.notesRef)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing git notes: %w", err)
+		}
+		deployments = notesToDeployments(notes, pattern)
+	} else {
+		refs, err := gdc.api.ListTags(ctx, dir)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing git tags: %w", err)
+		}
+		deployments = tagsToDeployments(refs, pattern)
+	}
+
+	slices.SortStableFunc(deployments, func(a, b *model.Deployment) int {
+		return b.SucceededAt.Compare(a.SucceededAt)
+	})
+	for i, d := range deployments {
+		d.ID = int64(len(deployments) - i)
+	}
+	return deployments, nil
+}
+
+// populateWithCommits sets the commits added and removed by each deployment compared to the next older one
+func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, dir string, deployments []*model.Deployment) ([]*model.Deployment, error) {
+	if len(deployments) <= 1 {
+		return deployments, nil
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(gdc.maxConcurrency)
+	start := time.Now()
+
+	for i := range len(deployments) - 1 {
+		g.Go(func() error {
+			d := deployments[i]
+			head := deployments[i].SHA
+			base := deployments[i+1].SHA
+			if head == base {
+				return nil
+			}
+
+			added, err := gdc.api.Log(gCtx, dir, base, head)
+			if err != nil {
+				return fmt.Errorf("error while comparing commits: %w", err)
+			}
+			d.Added = toCommits(added)
+
+			removed, err := gdc.api.Log(gCtx, dir, head, base)
+			if err != nil {
+				return fmt.Errorf("error comparing removed commits: %w", err)
+			}
+			d.Removed = toCommits(removed)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	log.Tracef("comparing %d times took %v", len(deployments)-1, time.Since(start))
+	return deployments, nil
+}
@@ -0,0 +1,189 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+var baseTime = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+func at(hours int) time.Time {
+	return baseTime.Add(time.Duration(hours) * time.Hour)
+}
+
+// testRepo is a repository with deterministic commit and tag dates
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T, dir string) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, dir: dir}
+	r.git(baseTime, "init", "-q", "-b", "main", dir)
+	return r
+}
+
+// git runs git in the repository with committer and tagger date set to date and returns its output
+func (r *testRepo) git(date time.Time, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	if args[0] != "init" {
+		cmd.Dir = r.dir
+	}
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_AUTHOR_DATE="+date.Format(time.RFC3339),
+		"GIT_COMMITTER_DATE="+date.Format(time.RFC3339),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit creates an empty commit with subject at date and returns its SHA
+func (r *testRepo) commit(subject string, date time.Time) string {
+	r.t.Helper()
+	r.git(date, "commit", "-q", "--allow-empty", "-m", subject)
+	return r.git(date, "rev-parse", "HEAD")
+}
+
+func newTestClient(t *testing.T, settings Settings) *DeploymentClient {
+	t.Helper()
+	api, err := NewAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewDeploymentClient(api, &config.Config{Env: "production"}, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func shas(deployments []*model.Deployment) []string {
+	result := make([]string, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.SHA
+	}
+	return result
+}
+
+func commitSHAs(commits []*model.Commit) []string {
+	result := make([]string, len(commits))
+	for i, c := range commits {
+		result[i] = c.SHA
+	}
+	return result
+}
+
+func TestListDeploymentsInRangeFromTags(t *testing.T) {
+	clones := t.TempDir()
+	repo := newTestRepo(t, filepath.Join(clones, "acme", "shop"))
+	c1 := repo.commit("first", at(1))
+	repo.git(at(1), "tag", "deploy/production/1")
+	c2 := repo.commit("second", at(2))
+	c3 := repo.commit("third", at(3))
+	repo.git(at(3), "tag", "deploy/production/2")
+	c4 := repo.commit("fourth", at(4))
+	repo.git(at(4), "tag", "deploy/staging/1")
+	// annotated tags are dated by the tagger
+	repo.git(at(5), "tag", "-a", "-m", "release", "deploy/production/3")
+
+	client := newTestClient(t, Settings{RepoPath: clones})
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Owner: "acme",
+		Repo:  "shop",
+		From:  at(1).Add(30 * time.Minute),
+		To:    at(6),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := shas(deployments), []string{c4, c3}; !slices.Equal(got, want) {
+		t.Fatalf("deployments = %v, want %v", got, want)
+	}
+	if !deployments[0].SucceededAt.Equal(at(5)) {
+		t.Errorf("annotated tag deployed at %v, want tagger date %v", deployments[0].SucceededAt, at(5))
+	}
+	if got, want := commitSHAs(deployments[0].Added), []string{c4}; !slices.Equal(got, want) {
+		t.Errorf("added by newest deployment = %v, want %v", got, want)
+	}
+	if got, want := commitSHAs(deployments[1].Added), []string{c3, c2}; !slices.Equal(got, want) {
+		t.Errorf("added by oldest deployment = %v, want %v compared to %s", got, want, c1)
+	}
+}
+
+func TestListDeploymentsInRangeFromNotes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	repo := newTestRepo(t, dir)
+	repo.commit("first", at(1))
+	c2 := repo.commit("second", at(2))
+	c3 := repo.commit("third", at(3))
+	repo.git(at(7), "notes", "--ref=deploys", "add", "-m", "deploy/production/7 "+at(7).Format(time.RFC3339), c2)
+	repo.git(at(3), "notes", "--ref=deploys", "add", "-m", "deploy/staging/1\ndeploy/production/8", c3)
+
+	// the repository path is the clone itself
+	client := newTestClient(t, Settings{RepoPath: dir, DeployNotesRef: "deploys"})
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Repo: "shop",
+		From: at(2),
+		To:   at(8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the time of the note line wins over the commit date, the rollback to c2 removed c3
+	if got, want := shas(deployments), []string{c2, c3}; !slices.Equal(got, want) {
+		t.Fatalf("deployments = %v, want %v", got, want)
+	}
+	if got, want := commitSHAs(deployments[0].Removed), []string{c3}; !slices.Equal(got, want) {
+		t.Errorf("removed by rollback = %v, want %v", got, want)
+	}
+}
+
+func TestListDeploymentsInRangeRejectsNamesOutsideRepoPath(t *testing.T) {
+	root := t.TempDir()
+	clones := filepath.Join(root, "clones")
+	if err := os.Mkdir(clones, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	secret := newTestRepo(t, filepath.Join(root, "secret"))
+	secret.commit("private", at(1))
+	secret.git(at(1), "tag", "deploy/production/1")
+
+	client := newTestClient(t, Settings{RepoPath: clones})
+	for _, q := range []models.DeploymentsQuery{
+		{Owner: "..", Repo: "secret"},
+		{Repo: "../secret"},
+		{Owner: "acme", Repo: ".."},
+		{Owner: "..", Repo: "."},
+	} {
+		q.From, q.To = at(0), at(2)
+		_, err := client.ListDeploymentsInRange(context.Background(), q)
+		if !errors.Is(err, model.ErrRepositoryNotFound) {
+			t.Errorf("%q/%q: error = %v, want model.ErrRepositoryNotFound", q.Owner, q.Repo, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// tagsToDeployments maps the tags matching pattern, each is a deployment of the commit it points to
+func tagsToDeployments(refs []*Ref, pattern string) []*model.Deployment {
+	var deployments []*model.Deployment
+	for _, ref := range refs {
+		if matched, _ := path.Match(pattern, ref.Name); matched {
+			deployments = append(deployments, newDeployment(ref.SHA, ref.Date))
+		}
+	}
+	return deployments
+}
+
+// notesToDeployments maps the lines of the notes matching pattern. A line is the name of a deployment,
+// optionally followed by the RFC3339 time it happened, e.g. "deploy/production/42 2024-05-01T12:00:00Z".
+// Without a time the committer date of the commit is used.
+func notesToDeployments(notes []*Note, pattern string) []*model.Deployment {
+	var deployments []*model.Deployment
+	for _, note := range notes {
+		for _, line := range strings.Split(note.Message, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if matched, _ := path.Match(pattern, fields[0]); !matched {
+				continue
+			}
+			date := note.CommitDate
+			if len(fields) > 1 {
+				if t, err := time.Parse(time.RFC3339, fields[1]); err == nil {
+					date = t
+				}
+			}
+			deployments = append(deployments, newDeployment(note.SHA, date))
+		}
+	}
+	return deployments
+}
+
+func newDeployment(sha string, date time.Time) *model.Deployment {
+	return &model.Deployment{
+		SHA:           sha,
+		CreatedAt:     date,
+		UpdatedAt:     date,
+		SucceededAt:   date,
+		ComparisonURL: "",
+		Added:         []*model.Commit{},
+		Removed:       []*model.Commit{},
+	}
+}
+
+func toCommits(commits []*Commit) []*model.Commit {
+	mapped := make([]*model.Commit, len(commits))
+	for i, commit := range commits {
+		mapped[i] = &model.Commit{
+			SHA:   commit.SHA,
+			Title: commit.Subject,
+			URL:   "",
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+)
+
+type MockGitClient struct {
+	prefix string
+}
+
+func NewMockAPI() API {
+	return &MockGitClient{
+		prefix: "deploy/production/",
+	}
+}
+
+func (gc *MockGitClient) ListTags(_ context.Context, _ string) ([]*Ref, error) {
+	time.Sleep(20 * time.Millisecond)
+
+	length := 100
+	if val, err := strconv.Atoi(os.Getenv("MOCK_DEPLOYMENTS_LENGTH")); err == nil {
+		length = val
+	}
+
+	// spread out tag dates randomly throughout the last 10 minutes
+	maxMs := (10 * time.Minute).Milliseconds()
+	refs := make([]*Ref, 0, length)
+	for i := range length {
+		refs = append(refs, &Ref{
+			Name: fmt.Sprintf("%s%d", gc.prefix, i),
+			SHA:  fmt.Sprintf("def456ghi%03d", i),
+			Date: time.Now().Add(-time.Duration(rand.Int64N(maxMs)) * time.Millisecond),
+		})
+	}
+	return refs, nil
+}
+
+func (gc *MockGitClient) ListNotes(_ context.Context, _, _ string) ([]*Note, error) {
+	time.Sleep(20 * time.Millisecond)
+	return []*Note{
+		{SHA: "def456ghi000", Message: gc.prefix + "0\n", CommitDate: time.Now().Add(-5 * time.Minute)},
+	}, nil
+}
+
+func (gc *MockGitClient) Log(_ context.Context, _, base, head string) ([]*Commit, error) {
+	time.Sleep(10 * time.Millisecond)
+	return []*Commit{
+		{SHA: head, Subject: fmt.Sprintf("feat: changes since %s", base)},
+	}, nil
+}
@@ -0,0 +1,23 @@
+package git
+
+import "time"
+
+// Ref is a tag of the local repository, SHA is the commit it points to
+type Ref struct {
+	Name string
+	SHA  string
+	// Date is the tagger date of annotated tags and the committer date of lightweight ones
+	Date time.Time
+}
+
+// Note is the git note attached to a commit
+type Note struct {
+	SHA        string
+	Message    string
+	CommitDate time.Time
+}
+
+type Commit struct {
+	SHA     string
+	Subject string
+}
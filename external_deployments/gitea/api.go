@@ -0,0 +1,210 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/github"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+// API mock for testing
+type API interface {
+	GetRepository(ctx context.Context, owner, repoName string) (*Repository, error)
+	ListReleases(ctx context.Context, owner, repoName string, opts *ListOptions) ([]*Release, *Response, error)
+	GetTag(ctx context.Context, owner, repoName, tag string) (*Tag, error)
+	CompareCommits(ctx context.Context, owner, repoName, base, head string) (*Comparison, error)
+}
+
+// ListOptions selects a page of a list request
+type ListOptions struct {
+	Page, Limit int
+}
+
+// Response holds the pagination of a list request
+type Response struct {
+	NextPage int
+}
+
+// ErrorResponse is returned for API responses with an error status
+type ErrorResponse struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("gitea API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client of the Gitea API, which Forgejo serves as well
+type Client struct {
+	client  *http.Client
+	baseURL *url.URL
+	token   secret.Secret
+}
+
+func NewAPI(conf *config.Config) (API, error) {
+	if len(conf.BaseURL) == 0 {
+		return nil, fmt.Errorf("no gitea base URL provided")
+	}
+	token, err := secret.New(conf.Token, conf.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := github.NewHTTPTransport(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewGiteaClient(&http.Client{Transport: transport}, conf.BaseURL, token)
+}
+
+// NewGiteaClient creates a client of the instance at baseURL, e.g. https://codeberg.org/api/v1.
+// The token is optional for public repositories.
+func NewGiteaClient(client *http.Client, baseURL string, token secret.Secret) (API, error) {
+	if client == nil {
+		return nil, fmt.Errorf("http client cannot be nil")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gitea base URL %s: %w", baseURL, err)
+	}
+	if !strings.HasSuffix(u.Path, "/api/v1") && !strings.HasSuffix(u.Path, "/api/v1/") {
+		u = u.JoinPath("api", "v1")
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &Client{
+		client:  client,
+		baseURL: u,
+		token:   token,
+	}, nil
+}
+
+func (gc *Client) GetRepository(ctx context.Context, owner, repoName string) (*Repository, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getRepository took %v\n", time.Since(start))
+	}()
+
+	var repo Repository
+	if _, err := gc.get(ctx, []string{"repos", owner, repoName}, nil, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+func (gc *Client) ListReleases(ctx context.Context, owner, repoName string, opts *ListOptions) ([]*Release, *Response, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("listReleases took %v\n", time.Since(start))
+	}()
+
+	var releases []*Release
+	resp, err := gc.list(ctx, []string{"repos", owner, repoName, "releases"}, opts, &releases)
+	return releases, resp, err
+}
+
+func (gc *Client) GetTag(ctx context.Context, owner, repoName, tag string) (*Tag, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getTag took %v\n", time.Since(start))
+	}()
+
+	var t Tag
+	if _, err := gc.get(ctx, []string{"repos", owner, repoName, "tags", tag}, nil, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (gc *Client) CompareCommits(ctx context.Context, owner, repoName, base, head string) (*Comparison, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("compareCommits took %v\n", time.Since(start))
+	}()
+
+	var cmp Comparison
+	if _, err := gc.get(ctx, []string{"repos", owner, repoName, "compare", base + "..." + head}, nil, &cmp); err != nil {
+		return nil, err
+	}
+	return &cmp, nil
+}
+
+func (gc *Client) list(ctx context.Context, path []string, opts *ListOptions, v any) (*Response, error) {
+	query := url.Values{}
+	if opts != nil && opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts != nil && opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	httpResp, err := gc.get(ctx, path, query, v)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{NextPage: nextPage(httpResp.Header.Get("Link"))}, nil
+}
+
+// get requests the escaped path segments relative to the base URL and decodes the JSON response into v
+func (gc *Client) get(ctx context.Context, path []string, query url.Values, v any) (*http.Response, error) {
+	u := gc.baseURL.JoinPath(path...)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := gc.token.Value()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := gc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, &ErrorResponse{StatusCode: resp.StatusCode, Message: body.Message}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("error while decoding gitea response of %s: %w", u.Path, err)
+	}
+	return resp, nil
+}
+
+// nextPage parses the page of the rel="next" link of a Link header, 0 if there is none
+func nextPage(link string) int {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return 0
+		}
+		page, _ := strconv.Atoi(u.Query().Get("page"))
+		return page
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+)
+
+func TestNewAPIAppliesHTTPSettings(t *testing.T) {
+	t.Run("proxy", func(t *testing.T) {
+		var host string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host = r.Host
+			_, _ = w.Write([]byte("{}"))
+		}))
+		t.Cleanup(proxy.Close)
+
+		api, err := NewAPI(&config.Config{BaseURL: "http://gitea.example.com", Token: "gitea-token", ProxyURL: proxy.URL})
+		if err != nil {
+			t.Fatalf("NewAPI() error = %v", err)
+		}
+		if _, err := api.GetRepository(context.Background(), "acme", "shop"); err != nil {
+			t.Fatalf("GetRepository() error = %v", err)
+		}
+		if host != "gitea.example.com" {
+			t.Errorf("proxied request to %q, want gitea.example.com", host)
+		}
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		api, err := NewAPI(&config.Config{BaseURL: server.URL, Token: "gitea-token", RequestTimeout: 20 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewAPI() error = %v", err)
+		}
+		start := time.Now()
+		if _, err := api.GetRepository(context.Background(), "acme", "shop"); err == nil {
+			t.Error("GetRepository() error = nil, want the request timed out")
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("failed after %v, want after the request timeout", elapsed)
+		}
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		if _, err := NewAPI(&config.Config{BaseURL: "https://gitea.example.com", Token: "gitea-token", CABundlePath: "/nonexistent/ca.pem"}); err == nil {
+			t.Error("NewAPI() error = nil, want the missing CA bundle reported")
+		}
+	})
+}
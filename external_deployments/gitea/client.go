@@ -0,0 +1,210 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+const (
+	defaultMaxConcurrency = 8
+	perPage               = 50
+)
+
+// DeploymentClient derives deployments from the releases of Gitea and Forgejo repositories,
+// each published release is a successful deployment of the commit its tag points to.
+// Drafts and pre-releases are skipped. It is safe for concurrent use.
+type DeploymentClient struct {
+	api            API
+	maxConcurrency int
+}
+
+func NewDeploymentClient(api API, conf *config.Config) (*DeploymentClient, error) {
+	if api == nil {
+		return nil, fmt.Errorf("api cannot be nil")
+	}
+	maxConcurrency := conf.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	return &DeploymentClient{
+		api:            api,
+		maxConcurrency: maxConcurrency,
+	}, nil
+}
+
+// ListDeploymentsInRange lists releases of q.Owner/q.Repo published in range [q.From, q.To]
+func (gdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	if q.Repo == "" {
+		return nil, fmt.Errorf("no repository set in query")
+	}
+
+	repo, err := gdc.api.GetRepository(ctx, q.Owner, q.Repo)
+	if err != nil {
+		var gtErr *ErrorResponse
+		if errors.As(err, &gtErr) && gtErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s/%s", model.ErrRepositoryNotFound, q.Owner, q.Repo)
+		}
+		return nil, err
+	}
+
+	successful, err := gdc.listPublishedUntil(ctx, q.Owner, q.Repo, q.From)
+	if err != nil {
+		return nil, err
+	}
+
+	inRange, oneBefore := model.SplitRange(successful, q.From, q.To)
+
+	if oneBefore != nil {
+		inRange = append(inRange, oneBefore)
+	}
+
+	populated, err := gdc.populateWithCommits(ctx, q.Owner, q.Repo, repo.HTMLURL, inRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove one before
+	if oneBefore != nil {
+		populated = populated[:len(populated)-1]
+	}
+	return populated, nil
+}
+
+// listPublishedUntil lists the published releases, newest first, until the first one published before from
+func (gdc *DeploymentClient) listPublishedUntil(ctx context.Context, owner, repo string, from time.Time) ([]*model.Deployment, error) {
+	var releases []*Release
+	opts := &ListOptions{Page: 1, Limit: perPage}
+
+	for opts.Page > 0 {
+		page, resp, err := gdc.api.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while fetching gitea releases: %w", err)
+		}
+
+		foundBefore := false
+		for _, release := range page {
+			if release.Draft || release.Prerelease {
+				continue
+			}
+			releases = append(releases, release)
+			foundBefore = foundBefore || release.PublishedAt.Before(from)
+		}
+		if foundBefore {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	shas, err := gdc.resolveTags(ctx, owner, repo, releases)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]*model.Deployment, 0, len(releases))
+	for _, release := range releases {
+		sha, ok := shas[release.TagName]
+		if !ok {
+			log.Warnf("tag %s of release %d in %s/%s not found, skipping it", release.TagName, release.ID, owner, repo)
+			continue
+		}
+		deployments = append(deployments, toDeployment(release, sha))
+	}
+	slices.SortFunc(deployments, func(a, b *model.Deployment) int {
+		return b.SucceededAt.Compare(a.SucceededAt)
+	})
+	return deployments, nil
+}
+
+// resolveTags returns the commit SHA per tag of releases, requesting each tag on its own as the tags of
+// a repository may be far more than its releases. Deleted tags are left out.
+func (gdc *DeploymentClient) resolveTags(ctx context.Context, owner, repo string, releases []*Release) (map[string]string, error) {
+	var names []string
+	for _, release := range releases {
+		if !slices.Contains(names, release.TagName) {
+			names = append(names, release.TagName)
+		}
+	}
+
+	var mu sync.Mutex
+	shas := make(map[string]string, len(names))
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(gdc.maxConcurrency)
+	for _, name := range names {
+		g.Go(func() error {
+			tag, err := gdc.api.GetTag(gCtx, owner, repo, name)
+			if err != nil {
+				var gtErr *ErrorResponse
+				if errors.As(err, &gtErr) && gtErr.StatusCode == http.StatusNotFound {
+					return nil
+				}
+				return fmt.Errorf("error while fetching gitea tag %s: %w", name, err)
+			}
+			if tag.Commit == nil {
+				return nil
+			}
+			mu.Lock()
+			shas[name] = tag.Commit.SHA
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return shas, nil
+}
+
+// populateWithCommits sets the commits added and removed by each deployment compared to the next older one.
+// Gitea compares from the merge base, so both directions are compared.
+func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, owner, repo, htmlURL string, deployments []*model.Deployment) ([]*model.Deployment, error) {
+	if len(deployments) <= 1 {
+		return deployments, nil
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(gdc.maxConcurrency)
+	start := time.Now()
+
+	for i := range len(deployments) - 1 {
+		g.Go(func() error {
+			d := deployments[i]
+			head := deployments[i].SHA
+			base := deployments[i+1].SHA
+			if head == base {
+				return nil
+			}
+			d.ComparisonURL = fmt.Sprintf("%s/compare/%s...%s", htmlURL, base, head)
+
+			addedCmp, err := gdc.api.CompareCommits(gCtx, owner, repo, base, head)
+			if err != nil {
+				return fmt.Errorf("error while comparing commits: %w", err)
+			}
+			d.Added = toCommits(addedCmp)
+
+			removedCmp, err := gdc.api.CompareCommits(gCtx, owner, repo, head, base)
+			if err != nil {
+				return fmt.Errorf("error comparing removed commits: %w", err)
+			}
+			d.Removed = toCommits(removedCmp)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	log.Tracef("comparing %d times took %v", len(deployments)-1, time.Since(start))
+	return deployments, nil
+}
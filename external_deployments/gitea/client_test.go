@@ -0,0 +1,244 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+var baseTime = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+// fakeGitea serves the repository acme/shop with its releases, pageSize per page, and the tags in tags.
+// It records the paths of the requests.
+type fakeGitea struct {
+	*httptest.Server
+
+	releases []*Release
+	pageSize int
+	tags     map[string]string
+	// tagStatus is the status of tag requests, 200 if not set
+	tagStatus int
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func newFakeGitea(t *testing.T) *fakeGitea {
+	t.Helper()
+	f := &fakeGitea{pageSize: 2, tags: make(map[string]string)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// release adds a release of tag, published hours after baseTime, newest first
+func (f *fakeGitea) release(id int64, tag string, hours int, draft, prerelease bool) {
+	publishedAt := baseTime.Add(time.Duration(hours) * time.Hour)
+	f.releases = append(f.releases, &Release{
+		ID:          id,
+		TagName:     tag,
+		Draft:       draft,
+		Prerelease:  prerelease,
+		CreatedAt:   publishedAt.Add(-time.Minute),
+		PublishedAt: publishedAt,
+	})
+	slices.SortStableFunc(f.releases, func(a, b *Release) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+}
+
+func (f *fakeGitea) requested() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.paths)
+}
+
+func (f *fakeGitea) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.paths = append(f.paths, r.URL.Path)
+	f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token gitea-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/api/v1/repos/acme/shop")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "repository not found"})
+		return
+	}
+
+	switch {
+	case path == "":
+		_ = json.NewEncoder(w).Encode(Repository{ID: 1, FullName: "acme/shop", HTMLURL: "https://gitea.example.com/acme/shop"})
+	case path == "/releases":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min((page-1)*f.pageSize, len(f.releases))
+		end := min(start+f.pageSize, len(f.releases))
+		if end < len(f.releases) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next", <%s%s?page=99>; rel="last"`, f.URL, r.URL.Path, page+1, f.URL, r.URL.Path))
+		}
+		_ = json.NewEncoder(w).Encode(f.releases[start:end])
+	case strings.HasPrefix(path, "/tags/"):
+		if f.tagStatus != 0 {
+			w.WriteHeader(f.tagStatus)
+			return
+		}
+		name := strings.TrimPrefix(path, "/tags/")
+		sha, ok := f.tags[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "tag not found"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Tag{Name: name, Commit: &TagCommit{SHA: sha}})
+	case strings.HasPrefix(path, "/compare/"):
+		// one commit named after the compared range
+		compared := strings.TrimPrefix(path, "/compare/")
+		_ = json.NewEncoder(w).Encode(Comparison{TotalCommits: 1, Commits: []*Commit{{
+			SHA:    compared,
+			Commit: &CommitMessage{Message: "compare " + compared + "\n\nwith body"},
+		}}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestClient(t *testing.T, f *fakeGitea) *DeploymentClient {
+	t.Helper()
+	api, err := NewGiteaClient(http.DefaultClient, f.URL, secret.Static("gitea-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewDeploymentClient(api, &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func ids(deployments []*model.Deployment) []int64 {
+	result := make([]int64, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.ID
+	}
+	return result
+}
+
+func TestListDeploymentsInRange(t *testing.T) {
+	f := newFakeGitea(t)
+	f.release(5, "v5", 14, false, false)
+	f.release(4, "v4", 13, true, false)
+	// its tag was deleted
+	f.release(3, "v3", 12, false, false)
+	f.release(6, "v6-rc", 11, false, true)
+	f.release(2, "v2", 10, false, false)
+	f.release(1, "v1", 8, false, false)
+	// on the page after the first release before the range
+	f.release(0, "v0", 6, false, false)
+	for _, tag := range []string{"v5", "v4", "v6-rc", "v2", "v1", "v0"} {
+		f.tags[tag] = "sha-" + tag
+	}
+	client := newTestClient(t, f)
+
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Owner: "acme",
+		Repo:  "shop",
+		From:  baseTime.Add(9 * time.Hour),
+		To:    baseTime.Add(15 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("ListDeploymentsInRange() error = %v", err)
+	}
+
+	if got, want := ids(deployments), []int64{5, 2}; !slices.Equal(got, want) {
+		t.Fatalf("deployments = %v, want %v", got, want)
+	}
+	newest := deployments[0]
+	if newest.SHA != "sha-v5" || !newest.SucceededAt.Equal(baseTime.Add(14*time.Hour)) {
+		t.Errorf("deployment 5 = %s at %v, want sha-v5 at %v", newest.SHA, newest.SucceededAt, baseTime.Add(14*time.Hour))
+	}
+	if want := "https://gitea.example.com/acme/shop/compare/sha-v2...sha-v5"; newest.ComparisonURL != want {
+		t.Errorf("ComparisonURL = %s, want %s", newest.ComparisonURL, want)
+	}
+	if len(newest.Added) != 1 || newest.Added[0].SHA != "sha-v2...sha-v5" || newest.Added[0].Title != "compare sha-v2...sha-v5" {
+		t.Errorf("Added = %+v, want the commit of sha-v2...sha-v5", newest.Added)
+	}
+	if len(newest.Removed) != 1 || newest.Removed[0].SHA != "sha-v5...sha-v2" {
+		t.Errorf("Removed = %+v, want the commit of sha-v5...sha-v2", newest.Removed)
+	}
+	// the oldest deployment is compared to the one before the range
+	if want := "https://gitea.example.com/acme/shop/compare/sha-v1...sha-v2"; deployments[1].ComparisonURL != want {
+		t.Errorf("ComparisonURL of the oldest deployment = %s, want %s", deployments[1].ComparisonURL, want)
+	}
+
+	var releasePages, tags []string
+	for _, path := range f.requested() {
+		switch {
+		case strings.HasSuffix(path, "/releases"):
+			releasePages = append(releasePages, path)
+		case strings.Contains(path, "/tags"):
+			tags = append(tags, strings.TrimPrefix(path, "/api/v1/repos/acme/shop/tags/"))
+		}
+	}
+	if len(releasePages) != 3 {
+		t.Errorf("listed %d pages of releases, want 3", len(releasePages))
+	}
+	slices.Sort(tags)
+	if want := []string{"v1", "v2", "v3", "v5"}; !slices.Equal(tags, want) {
+		t.Errorf("requested tags %v, want only the ones of the published releases %v", tags, want)
+	}
+}
+
+func TestListDeploymentsInRangeRepositoryNotFound(t *testing.T) {
+	client := newTestClient(t, newFakeGitea(t))
+
+	_, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{Owner: "acme", Repo: "cart", From: baseTime, To: baseTime.Add(time.Hour)})
+	if !errors.Is(err, model.ErrRepositoryNotFound) {
+		t.Errorf("error = %v, want model.ErrRepositoryNotFound", err)
+	}
+}
+
+func TestListDeploymentsInRangeFailsOnTagErrors(t *testing.T) {
+	f := newFakeGitea(t)
+	f.release(1, "v1", 8, false, false)
+	f.tagStatus = http.StatusInternalServerError
+	client := newTestClient(t, f)
+
+	_, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{Owner: "acme", Repo: "shop", From: baseTime, To: baseTime.Add(24 * time.Hour)})
+	var gtErr *ErrorResponse
+	if !errors.As(err, &gtErr) || gtErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("error = %v, want the error of the tag request", err)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		link string
+		want int
+	}{
+		{link: "", want: 0},
+		{link: `<https://gitea.example.com/api/v1/repos/acme/shop/releases?page=3&limit=50>; rel="next", <https://gitea.example.com/api/v1/repos/acme/shop/releases?page=9&limit=50>; rel="last"`, want: 3},
+		{link: `<https://gitea.example.com/api/v1/repos/acme/shop/releases?page=1&limit=50>; rel="first", <https://gitea.example.com/api/v1/repos/acme/shop/releases?page=2&limit=50>; rel="prev"`, want: 0},
+	}
+	for _, tt := range tests {
+		if got := nextPage(tt.link); got != tt.want {
+			t.Errorf("nextPage(%q) = %d, want %d", tt.link, got, tt.want)
+		}
+	}
+}
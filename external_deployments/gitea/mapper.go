@@ -0,0 +1,45 @@
+package gitea
+
+import (
+	"strings"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// toDeployment maps a published release, its SucceededAt is the time it was published
+func toDeployment(release *Release, sha string) *model.Deployment {
+	if release == nil {
+		return nil
+	}
+	return &model.Deployment{
+		ID:            release.ID,
+		SHA:           sha,
+		CreatedAt:     release.CreatedAt,
+		UpdatedAt:     release.PublishedAt,
+		SucceededAt:   release.PublishedAt,
+		ComparisonURL: "",
+		Added:         []*model.Commit{},
+		Removed:       []*model.Commit{},
+	}
+}
+
+func toCommits(cmp *Comparison) []*model.Commit {
+	commits := make([]*model.Commit, len(cmp.Commits))
+	for i, commit := range cmp.Commits {
+		commits[i] = toCommit(commit)
+	}
+	return commits
+}
+
+func toCommit(commit *Commit) *model.Commit {
+	var message string
+	if commit.Commit != nil {
+		message = commit.Commit.Message
+	}
+	title, _, _ := strings.Cut(message, "\n")
+	return &model.Commit{
+		SHA:   commit.SHA,
+		Title: title,
+		URL:   commit.HTMLURL,
+	}
+}
@@ -0,0 +1,105 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type MockGiteaClient struct {
+	owner string
+}
+
+func NewMockAPI() API {
+	return &MockGiteaClient{
+		owner: "mock-owner",
+	}
+}
+
+func (gc *MockGiteaClient) GetRepository(_ context.Context, owner, repoName string) (*Repository, error) {
+	time.Sleep(300 * time.Millisecond)
+
+	if owner == "" {
+		owner = gc.owner
+	}
+	return &Repository{
+		ID:       123456,
+		FullName: owner + "/" + repoName,
+		HTMLURL:  "https://codeberg.org/" + owner + "/" + repoName,
+	}, nil
+}
+
+func mockLength() int {
+	length := 100
+	if val, err := strconv.Atoi(os.Getenv("MOCK_DEPLOYMENTS_LENGTH")); err == nil {
+		length = val
+	}
+	return length
+}
+
+func (gc *MockGiteaClient) ListReleases(_ context.Context, _, _ string, _ *ListOptions) ([]*Release, *Response, error) {
+	time.Sleep(400 * time.Millisecond)
+
+	length := mockLength()
+	releases := make([]*Release, 0, length)
+
+	// spread out publish timestamps randomly throughout the last 10 minutes
+	maxMs := (10 * time.Minute).Milliseconds()
+	for i := range length {
+		publishedAt := time.Now().Add(-time.Duration(rand.Int64N(maxMs)) * time.Millisecond)
+		releases = append(releases, &Release{
+			ID:              int64(1001 + i),
+			TagName:         fmt.Sprintf("v1.0.%d", i),
+			TargetCommitish: "main",
+			Name:            fmt.Sprintf("Mocked release %d", i+1),
+			CreatedAt:       publishedAt.Add(-time.Minute),
+			PublishedAt:     publishedAt,
+		})
+	}
+	slices.SortFunc(releases, func(a, b *Release) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+
+	return releases, &Response{NextPage: 0}, nil
+}
+
+func (gc *MockGiteaClient) GetTag(_ context.Context, _, _, tag string) (*Tag, error) {
+	time.Sleep(100 * time.Millisecond)
+
+	var i int
+	if _, err := fmt.Sscanf(tag, "v1.0.%d", &i); err != nil {
+		return nil, &ErrorResponse{StatusCode: http.StatusNotFound, Message: "tag " + tag + " not found"}
+	}
+	return &Tag{
+		Name:   tag,
+		Commit: &TagCommit{SHA: fmt.Sprintf("def456ghi%03d", i)},
+	}, nil
+}
+
+func (gc *MockGiteaClient) CompareCommits(_ context.Context, owner, repoName, _, _ string) (*Comparison, error) {
+	time.Sleep(300 * time.Millisecond)
+
+	if owner == "" {
+		owner = gc.owner
+	}
+	return &Comparison{
+		TotalCommits: 2,
+		Commits: []*Commit{
+			{
+				SHA:     "def456ghi789",
+				HTMLURL: "https://codeberg.org/" + owner + "/" + repoName + "/commit/def456ghi789",
+				Commit:  &CommitMessage{Message: "feat: mocked commit 1"},
+			},
+			{
+				SHA:     "ghi789jkl012",
+				HTMLURL: "https://codeberg.org/" + owner + "/" + repoName + "/commit/ghi789jkl012",
+				Commit:  &CommitMessage{Message: "fix: mocked commit 2\n\nwith body"},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,47 @@
+package gitea
+
+import "time"
+
+// Repository of the Gitea API, see https://docs.gitea.com/api/
+type Repository struct {
+	ID       int64  `json:"id"`
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type Release struct {
+	ID              int64     `json:"id"`
+	TagName         string    `json:"tag_name"`
+	TargetCommitish string    `json:"target_commitish"`
+	Name            string    `json:"name"`
+	HTMLURL         string    `json:"html_url"`
+	Draft           bool      `json:"draft"`
+	Prerelease      bool      `json:"prerelease"`
+	CreatedAt       time.Time `json:"created_at"`
+	PublishedAt     time.Time `json:"published_at"`
+}
+
+type Tag struct {
+	Name   string     `json:"name"`
+	Commit *TagCommit `json:"commit"`
+}
+
+type TagCommit struct {
+	SHA     string    `json:"sha"`
+	Created time.Time `json:"created"`
+}
+
+type Comparison struct {
+	TotalCommits int       `json:"total_commits"`
+	Commits      []*Commit `json:"commits"`
+}
+
+type Commit struct {
+	SHA     string         `json:"sha"`
+	HTMLURL string         `json:"html_url"`
+	Commit  *CommitMessage `json:"commit"`
+}
+
+type CommitMessage struct {
+	Message string `json:"message"`
+}
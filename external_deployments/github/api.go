@@ -3,20 +3,25 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v81/github"
 
 	"github.com/kemonprogrammer/github-go-client/config"
 	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/secret"
 )
 
 // API mock for testing
 type API interface {
-	GetRepository(ctx context.Context, repoName string) (*github.Repository, *github.Response, error)
-	ListDeployments(ctx context.Context, repoName string, opts *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error)
-	ListDeploymentStatuses(ctx context.Context, repoName string, id int64, opts *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error)
-	CompareCommits(ctx context.Context, repoName, base, head string, opts *github.ListOptions) (*github.CommitsComparison, error)
+	GetRepository(ctx context.Context, owner, repoName string) (*github.Repository, *github.Response, error)
+	ListDeployments(ctx context.Context, owner, repoName string, opts *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error)
+	ListDeploymentStatuses(ctx context.Context, owner, repoName string, id int64, opts *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error)
+	CompareCommits(ctx context.Context, owner, repoName, base, head string, opts *github.ListOptions) (*github.CommitsComparison, error)
 }
 
 type Client struct {
@@ -32,16 +37,34 @@ func NewAPI(conf *config.Config) (API, error) {
 	if len(env) == 0 {
 		env = "production"
 	}
-	if len(githubPat) == 0 {
+	if len(githubPat) == 0 && len(conf.TokenFile) == 0 && len(conf.Tokens) == 0 && conf.AppID == 0 {
 		return nil, fmt.Errorf("no external deployments auth token provided")
 	}
+	log.Debugf("github owner %s, environment %s", owner, env)
+
+	apiURL, uploadURL := defaultAPIURL, ""
+	if conf.BaseURL != "" {
+		var err error
+		apiURL, uploadURL, err = enterpriseURLs(conf.BaseURL, conf.UploadURL)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	//todo remove
-	fmt.Printf("Github PAT from config: %s\n", githubPat)
-	fmt.Printf("owner from config: %s\n", owner)
-	fmt.Printf("env from config: %s\n", env)
+	transport, err := sharedTransport(conf, apiURL)
+	if err != nil {
+		return nil, err
+	}
 
-	gh := github.NewClient(nil).WithAuthToken(githubPat)
+	gh := github.NewClient(&http.Client{Transport: transport})
+	if conf.BaseURL != "" {
+		gh, err = gh.WithEnterpriseURLs(apiURL, uploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid github enterprise URLs: %w", err)
+		}
+	}
+	// the RateLimitTransport waits for the reset instead of failing early
+	gh.DisableRateLimitCheck = true
 	clientInterface, err := NewGithubClient(gh, owner, env)
 	if err != nil {
 		return nil, err
@@ -61,19 +84,27 @@ func NewGithubClient(client *github.Client, owner, environment string) (API, err
 	}, nil
 }
 
-func (gc *Client) GetRepository(ctx context.Context, repoName string) (*github.Repository, *github.Response, error) {
+// ownerOr returns owner, or the configured owner if owner is empty
+func (gc *Client) ownerOr(owner string) string {
+	if owner == "" {
+		return gc.owner
+	}
+	return owner
+}
+
+func (gc *Client) GetRepository(ctx context.Context, owner, repoName string) (*github.Repository, *github.Response, error) {
 	start := time.Now()
 	defer func() {
 		log.Tracef("getRepository took %v\n", time.Since(start))
 	}()
-	repo, resp, err := gc.client.Repositories.Get(ctx, gc.owner, repoName)
+	repo, resp, err := gc.client.Repositories.Get(ctx, gc.ownerOr(owner), repoName)
 	if err != nil {
 		return nil, nil, err
 	}
 	return repo, resp, nil
 }
 
-func (gc *Client) ListDeployments(ctx context.Context, repoName string, opts *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error) {
+func (gc *Client) ListDeployments(ctx context.Context, owner, repoName string, opts *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error) {
 	start := time.Now()
 	defer func() {
 		log.Tracef("")
@@ -82,28 +113,131 @@ func (gc *Client) ListDeployments(ctx context.Context, repoName string, opts *gi
 	if opts.Environment == "" {
 		opts.Environment = gc.environment
 	}
-	deploys, resp, err := gc.client.Repositories.ListDeployments(ctx, gc.owner, repoName, opts)
+	deploys, resp, err := gc.client.Repositories.ListDeployments(ctx, gc.ownerOr(owner), repoName, opts)
 	return deploys, resp, err
 }
 
-func (gc *Client) ListDeploymentStatuses(ctx context.Context, repoName string, id int64, opts *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error) {
+func (gc *Client) ListDeploymentStatuses(ctx context.Context, owner, repoName string, id int64, opts *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error) {
 	start := time.Now()
 	defer func() {
 		log.Tracef("listDeploymentStatuses took %v\n", time.Since(start))
 	}()
-	statuses, resp, err := gc.client.Repositories.ListDeploymentStatuses(ctx, gc.owner, repoName, id, opts)
+	statuses, resp, err := gc.client.Repositories.ListDeploymentStatuses(ctx, gc.ownerOr(owner), repoName, id, opts)
 	return statuses, resp, err
 }
 
-func (gc *Client) CompareCommits(ctx context.Context, repoName, base, head string, opts *github.ListOptions) (*github.CommitsComparison, error) {
+func (gc *Client) CompareCommits(ctx context.Context, owner, repoName, base, head string, opts *github.ListOptions) (*github.CommitsComparison, error) {
 	start := time.Now()
 	defer func() {
 		log.Tracef("compareCommits took %v\n", time.Since(start))
 	}()
-	commitCmp, _, err := gc.client.Repositories.CompareCommits(ctx, gc.owner, repoName, base, head, opts)
+	commitCmp, _, err := gc.client.Repositories.CompareCommits(ctx, gc.ownerOr(owner), repoName, base, head, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	return commitCmp, err
 }
+
+// transportKey holds the settings of conf the transport is built from
+type transportKey struct {
+	apiURL, token, tokenFile, tokens        string
+	appID, appInstallationID                int64
+	appPrivateKeyPath, appOwner             string
+	caBundlePath, proxyURL                  string
+	connectTimeout, requestTimeout, maxWait time.Duration
+	rateLimitWait                           bool
+	maxRetries                              int
+}
+
+var (
+	sharedTransportsMu sync.Mutex
+	sharedTransports   = make(map[transportKey]http.RoundTripper)
+)
+
+// sharedTransport returns the transport authenticating requests to apiURL with the credentials of conf.
+// It is built once per distinct settings, so all providers using GitHub, e.g. the comparers of the argocd
+// and flux providers, share the rate limits and remembered responses of the credentials.
+func sharedTransport(conf *config.Config, apiURL string) (http.RoundTripper, error) {
+	key := transportKey{
+		apiURL:            apiURL,
+		token:             conf.Token,
+		tokenFile:         conf.TokenFile,
+		tokens:            strings.Join(conf.Tokens, ","),
+		appID:             conf.AppID,
+		appInstallationID: conf.AppInstallationID,
+		appPrivateKeyPath: conf.AppPrivateKeyPath,
+		appOwner:          conf.Owner,
+		caBundlePath:      conf.CABundlePath,
+		proxyURL:          conf.ProxyURL,
+		connectTimeout:    conf.ConnectTimeout,
+		requestTimeout:    conf.RequestTimeout,
+		maxWait:           conf.RateLimitMaxWait,
+		rateLimitWait:     conf.RateLimitWait,
+		maxRetries:        conf.MaxRetries,
+	}
+	if conf.AppID == 0 {
+		// the owner only selects the installation of the app
+		key.appOwner = ""
+	}
+
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+	if transport, ok := sharedTransports[key]; ok {
+		return transport, nil
+	}
+	transport, err := newTransport(conf, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	sharedTransports[key] = transport
+	return transport, nil
+}
+
+// newTransport pools a RateLimitTransport per credential of conf behind a ConditionalTransport
+func newTransport(conf *config.Config, apiURL string) (http.RoundTripper, error) {
+	httpTransport, err := NewHTTPTransport(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []secret.Secret
+	if len(conf.Token) > 0 || len(conf.TokenFile) > 0 {
+		token, err := secret.New(conf.Token, conf.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	for _, token := range conf.Tokens {
+		if token != "" {
+			tokens = append(tokens, secret.Static(token))
+		}
+	}
+
+	// every credential, and every installation of the app, has its own rate limit
+	var limiters []Credential
+	for _, token := range tokens {
+		auth, err := newTokenTransport(httpTransport, apiURL, token)
+		if err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, NewRateLimitTransport(auth, conf))
+	}
+	if conf.AppID != 0 {
+		key, err := os.ReadFile(conf.AppPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading github app private key: %w", err)
+		}
+		auth, err := NewAppTransport(httpTransport, apiURL, conf.AppID, key, conf.AppInstallationID, conf.Owner)
+		if err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, NewInstallationsTransport(auth, conf))
+	}
+	pool, err := NewTokenPoolTransport(limiters)
+	if err != nil {
+		return nil, err
+	}
+	return NewConditionalTransport(pool), nil
+}
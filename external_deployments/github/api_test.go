@@ -0,0 +1,33 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+)
+
+func transportOf(t *testing.T, conf *config.Config) any {
+	t.Helper()
+	api, err := NewAPI(conf)
+	if err != nil {
+		t.Fatalf("NewAPI() error = %v", err)
+	}
+	return api.(*Client).client.Client().Transport
+}
+
+func TestNewAPISharesTransportOfCredentials(t *testing.T) {
+	conf := &config.Config{Owner: "o", Token: "shared-token", Provider: "github"}
+	// the routing client creates the clients of other providers from a copy of the config
+	argoConf := *conf
+	argoConf.Provider = "argocd"
+	otherConf := *conf
+	otherConf.Token = "other-token"
+
+	first := transportOf(t, conf)
+	if got := transportOf(t, &argoConf); got != first {
+		t.Error("clients with the same credentials use separate transports, want the pool shared")
+	}
+	if got := transportOf(t, &otherConf); got == first {
+		t.Error("clients with other credentials share a transport")
+	}
+}
@@ -0,0 +1,416 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+const (
+	defaultAPIURL = "https://api.github.com/"
+	// jwtLifetime is below GitHub's maximum of 10 minutes to allow for clock drift
+	jwtLifetime = 9 * time.Minute
+	// tokenRefreshMargin is how long before their expiry installation tokens are refreshed
+	tokenRefreshMargin = 5 * time.Minute
+	// installationsReloadInterval limits reloading the installations for unknown owners
+	installationsReloadInterval = time.Minute
+	// appLoadTimeout limits a token or installations load shared by concurrent requests
+	appLoadTimeout = time.Minute
+)
+
+// AppTransport authenticates requests as a GitHub App installation. It mints JWTs signed with the
+// app's private key, exchanges them for installation tokens and refreshes the tokens before they expire.
+//
+// The installation is picked by the owner of the requested repository, unless a fixed installation is set.
+// Requests to other hosts than the API are sent without a token.
+type AppTransport struct {
+	base           http.RoundTripper
+	apiURL         *url.URL
+	appID          int64
+	key            *rsa.PrivateKey
+	installationID int64
+	defaultOwner   string
+
+	mu sync.Mutex
+	// installations maps the account login of each installation to its ID
+	installations       map[string]int64
+	installationsLoaded time.Time
+	tokens              map[int64]*installationToken
+	loads               singleflight.Group
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type installation struct {
+	ID      int64 `json:"id"`
+	Account struct {
+		Login string `json:"login"`
+	} `json:"account"`
+}
+
+// NewAppTransport authenticates as installation installationID of the app, or the installation of the
+// requested owner if installationID is 0. apiURL defaults to https://api.github.com/, requests without
+// an owner in their path use defaultOwner.
+func NewAppTransport(base http.RoundTripper, apiURL string, appID int64, privateKey []byte, installationID int64, defaultOwner string) (*AppTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if appID <= 0 {
+		return nil, fmt.Errorf("no github app ID provided")
+	}
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid github API URL %s: %w", apiURL, err)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &AppTransport{
+		base:           base,
+		apiURL:         u,
+		appID:          appID,
+		key:            key,
+		installationID: installationID,
+		defaultOwner:   defaultOwner,
+		installations:  make(map[string]int64),
+		tokens:         make(map[int64]*installationToken),
+	}, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("github app private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse github app private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("github app private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func (t *AppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.apiURL.Host {
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.token(req.Context(), t.owner(req.URL))
+	if err != nil {
+		return nil, err
+	}
+
+	// a RoundTripper must not modify the request
+	authReq := req.Clone(req.Context())
+	authReq.Header.Set("Authorization", "token "+token)
+	return t.base.RoundTrip(authReq)
+}
+
+// owner returns the owner of a /repos/{owner}/{repo} path, or the default owner for other paths
+func (t *AppTransport) owner(u *url.URL) string {
+	path := strings.TrimPrefix(u.Path, t.apiURL.Path)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "repos" {
+		return segments[1]
+	}
+	return t.defaultOwner
+}
+
+// token returns a valid token of the installation of owner, minting a new one if none is cached
+// or it expires within tokenRefreshMargin
+func (t *AppTransport) token(ctx context.Context, owner string) (string, error) {
+	id, err := t.installation(ctx, owner)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	cached, ok := t.tokens[id]
+	t.mu.Unlock()
+	if ok && time.Until(cached.ExpiresAt) > tokenRefreshMargin {
+		return cached.Token, nil
+	}
+
+	v, err := t.sharedLoad(ctx, "token/"+strconv.FormatInt(id, 10), func(loadCtx context.Context) (any, error) {
+		token, err := t.createToken(loadCtx, id)
+		if err != nil {
+			return nil, err
+		}
+		log.AddSecret(token.Token)
+		t.mu.Lock()
+		t.tokens[id] = token
+		t.mu.Unlock()
+		log.Debugf("created github app installation token for installation %d, expires at %v", id, token.ExpiresAt)
+		return token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(*installationToken).Token, nil
+}
+
+// sharedLoad runs load once for concurrent callers with the same key. The load is not canceled with the
+// context of the caller starting it, so the other callers are not failed by it, but limited by appLoadTimeout.
+// Each caller stops waiting once its own ctx is done.
+func (t *AppTransport) sharedLoad(ctx context.Context, key string, load func(ctx context.Context) (any, error)) (any, error) {
+	ch := t.loads.DoChan(key, func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), appLoadTimeout)
+		defer cancel()
+		return load(loadCtx)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// knownInstallation returns the ID of the installation of owner without loading the installations
+func (t *AppTransport) knownInstallation(owner string) (int64, bool) {
+	if t.installationID > 0 {
+		return t.installationID, true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, ok := t.installations[strings.ToLower(owner)]
+	return id, ok
+}
+
+// installation returns the ID of the installation of owner, reloading the installations of the app
+// at most every installationsReloadInterval if owner is unknown
+func (t *AppTransport) installation(ctx context.Context, owner string) (int64, error) {
+	if t.installationID > 0 {
+		return t.installationID, nil
+	}
+	owner = strings.ToLower(owner)
+
+	t.mu.Lock()
+	id, ok := t.installations[owner]
+	reload := time.Since(t.installationsLoaded) > installationsReloadInterval
+	t.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	if reload {
+		_, err := t.sharedLoad(ctx, "installations", func(loadCtx context.Context) (any, error) {
+			return nil, t.loadInstallations(loadCtx)
+		})
+		if err != nil {
+			return 0, err
+		}
+		t.mu.Lock()
+		id, ok = t.installations[owner]
+		t.mu.Unlock()
+		if ok {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: github app %d is not installed for %s", model.ErrRepositoryNotFound, t.appID, owner)
+}
+
+// InstallationsTransport governs the requests of a GitHub App by a RateLimitTransport per installation,
+// as every installation has its own rate limit. Requests to other hosts than the API are not governed.
+type InstallationsTransport struct {
+	app  *AppTransport
+	conf *config.Config
+
+	mu       sync.Mutex
+	limiters map[int64]*RateLimitTransport
+}
+
+func NewInstallationsTransport(app *AppTransport, conf *config.Config) *InstallationsTransport {
+	return &InstallationsTransport{
+		app:      app,
+		conf:     conf,
+		limiters: make(map[int64]*RateLimitTransport),
+	}
+}
+
+func (t *InstallationsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.app.apiURL.Host {
+		return t.app.RoundTrip(req)
+	}
+	id, err := t.app.installation(req.Context(), t.app.owner(req.URL))
+	if err != nil {
+		return nil, err
+	}
+	return t.limiter(id).RoundTrip(req)
+}
+
+// limiter returns the RateLimitTransport of installation id, creating it on first use
+func (t *InstallationsTransport) limiter(id int64) *RateLimitTransport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limiter, ok := t.limiters[id]
+	if !ok {
+		limiter = NewRateLimitTransport(t.app, t.conf)
+		t.limiters[id] = limiter
+	}
+	return limiter
+}
+
+// budget returns the budget of the installation req is sent with, it is unknown before its first request
+func (t *InstallationsTransport) budget(req *http.Request, now time.Time) (int, time.Time) {
+	if req.URL.Host != t.app.apiURL.Host {
+		return math.MaxInt, time.Time{}
+	}
+	id, ok := t.app.knownInstallation(t.app.owner(req.URL))
+	if !ok {
+		return math.MaxInt, time.Time{}
+	}
+	t.mu.Lock()
+	limiter, ok := t.limiters[id]
+	t.mu.Unlock()
+	if !ok {
+		return math.MaxInt, time.Time{}
+	}
+	return limiter.budget(req, now)
+}
+
+// loadInstallations lists the installations of the app, authenticated as the app itself
+func (t *AppTransport) loadInstallations(ctx context.Context) error {
+	installations := make(map[string]int64)
+	for page := 1; page > 0; {
+		var batch []installation
+		next, err := t.appRequest(ctx, http.MethodGet, "app/installations?per_page=100&page="+strconv.Itoa(page), &batch)
+		if err != nil {
+			return fmt.Errorf("error while listing github app installations: %w", err)
+		}
+		for _, inst := range batch {
+			installations[strings.ToLower(inst.Account.Login)] = inst.ID
+		}
+		page = next
+	}
+
+	t.mu.Lock()
+	t.installations = installations
+	t.installationsLoaded = time.Now()
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *AppTransport) createToken(ctx context.Context, id int64) (*installationToken, error) {
+	var token installationToken
+	_, err := t.appRequest(ctx, http.MethodPost, fmt.Sprintf("app/installations/%d/access_tokens", id), &token)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating github app installation token: %w", err)
+	}
+	return &token, nil
+}
+
+// appRequest sends a request authenticated with a JWT of the app and decodes the response into v.
+// It returns the next page of list responses, 0 for the last page.
+func (t *AppTransport) appRequest(ctx context.Context, method, path string, v any) (int, error) {
+	jwt, err := t.jwt(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	u, err := t.apiURL.Parse(path)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("github API returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return 0, err
+	}
+	return nextPage(resp.Header.Get("Link")), nil
+}
+
+// jwt returns a JSON Web Token of the app signed with RS256
+func (t *AppTransport) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		// backdated to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(t.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error while signing github app JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// nextPage returns the page of the rel="next" link of a Link header, 0 if there is none
+func nextPage(link string) int {
+	for _, part := range strings.Split(link, ",") {
+		target, rel, found := strings.Cut(part, ";")
+		if !found || !strings.Contains(rel, `rel="next"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return 0
+		}
+		page, _ := strconv.Atoi(u.Query().Get("page"))
+		return page
+	}
+	return 0
+}
@@ -0,0 +1,283 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// fakeTokenEndpoint serves the installations acme and other of app 42 on two pages and mints
+// installation tokens tok-<installation>-<n> expiring after expiry. Repository requests echo their token,
+// they are rate limited if sent with a token of the drained installation.
+type fakeTokenEndpoint struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu      sync.Mutex
+	expiry  time.Duration
+	minted  map[string]int
+	drained string
+
+	// minting receives a value when a token is requested, release blocks minting until closed, if set
+	minting chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/app/installations":
+		if !f.validJWT(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/app/installations?per_page=100&page=2>; rel="next"`, r.Host))
+			_, _ = fmt.Fprint(w, `[{"id": 1, "account": {"login": "acme"}}]`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[{"id": 2, "account": {"login": "Other"}}]`)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/app/installations/"):
+		if !f.validJWT(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if f.minting != nil {
+			f.minting <- struct{}{}
+			<-f.release
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/app/installations/"), "/access_tokens")
+		f.mu.Lock()
+		f.minted[id]++
+		token := installationToken{
+			Token:     fmt.Sprintf("tok-%s-%d", id, f.minted[id]),
+			ExpiresAt: time.Now().Add(f.expiry),
+		}
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(token)
+	default:
+		token := r.Header.Get("Authorization")
+		w.Header().Set("X-Token", token)
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		f.mu.Lock()
+		drained := f.drained != "" && strings.HasPrefix(token, "token tok-"+f.drained+"-")
+		f.mu.Unlock()
+		if drained {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4000")
+	}
+}
+
+// validJWT verifies the signature and issuer of the JWT of the app
+func (f *fakeTokenEndpoint) validJWT(r *http.Request) bool {
+	jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if !ok || len(parts) != 3 {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+		f.t.Errorf("invalid JWT signature: %v", err)
+		return false
+	}
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var c struct {
+		Iss string `json:"iss"`
+	}
+	return json.Unmarshal(claims, &c) == nil && c.Iss == "42"
+}
+
+func (f *fakeTokenEndpoint) setExpiry(expiry time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expiry = expiry
+}
+
+func newTestAppTransport(t *testing.T, installationID int64) (*AppTransport, *httptest.Server) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := &fakeTokenEndpoint{t: t, key: &key.PublicKey, expiry: time.Hour, minted: make(map[string]int)}
+	server := httptest.NewServer(endpoint)
+	t.Cleanup(server.Close)
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	transport, err := NewAppTransport(nil, server.URL, 42, pemKey, installationID, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport, server
+}
+
+// tokenOf returns the token the request to path was sent with
+func tokenOf(t *testing.T, client *http.Client, url string) (string, error) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	return resp.Header.Get("X-Token"), nil
+}
+
+func TestAppTransportPicksInstallationOfOwner(t *testing.T) {
+	transport, server := newTestAppTransport(t, 0)
+	client := &http.Client{Transport: transport}
+
+	for path, want := range map[string]string{
+		"/repos/acme/shop/deployments": "token tok-1-1",
+		"/repos/other/shop":            "token tok-2-1",
+		// requests without an owner use the default owner
+		"/rate_limit": "token tok-1-1",
+	} {
+		if got, err := tokenOf(t, client, server.URL+path); err != nil || got != want {
+			t.Errorf("%s sent with %q, error %v, want %q", path, got, err, want)
+		}
+	}
+
+	_, err := tokenOf(t, client, server.URL+"/repos/unknown/shop")
+	if !errors.Is(err, model.ErrRepositoryNotFound) {
+		t.Errorf("error of owner without installation = %v, want model.ErrRepositoryNotFound", err)
+	}
+}
+
+func TestAppTransportRefreshesExpiringToken(t *testing.T) {
+	transport, server := newTestAppTransport(t, 1)
+	endpoint := server.Config.Handler.(*fakeTokenEndpoint)
+	client := &http.Client{Transport: transport}
+	url := server.URL + "/repos/acme/shop"
+
+	// the token expires within the refresh margin, the next request mints a new one
+	endpoint.setExpiry(tokenRefreshMargin - time.Minute)
+	for _, want := range []string{"token tok-1-1", "token tok-1-2"} {
+		if got, err := tokenOf(t, client, url); err != nil || got != want {
+			t.Errorf("sent with %q, error %v, want %q", got, err, want)
+		}
+	}
+
+	endpoint.setExpiry(time.Hour)
+	for _, want := range []string{"token tok-1-3", "token tok-1-3"} {
+		if got, err := tokenOf(t, client, url); err != nil || got != want {
+			t.Errorf("sent with %q, error %v, want %q", got, err, want)
+		}
+	}
+}
+
+func TestAppTransportSendsNoTokenToOtherHosts(t *testing.T) {
+	transport, _ := newTestAppTransport(t, 1)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Token", r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, other.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if got := resp.Header.Get("X-Token"); got != "" {
+		t.Errorf("request to other host sent with %q, want no token", got)
+	}
+}
+
+func TestInstallationsHaveSeparateRateLimits(t *testing.T) {
+	app, server := newTestAppTransport(t, 0)
+	endpoint := server.Config.Handler.(*fakeTokenEndpoint)
+	endpoint.drained = "1"
+	installations := NewInstallationsTransport(app, &config.Config{})
+	pool, err := NewTokenPoolTransport([]Credential{installations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: pool}
+
+	_, err = tokenOf(t, client, server.URL+"/repos/acme/shop")
+	var rateLimitErr *model.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("error of drained installation = %v, want *model.RateLimitError", err)
+	}
+	// the drained installation of acme does not block the installation of other
+	if got, err := tokenOf(t, client, server.URL+"/repos/other/shop"); err != nil || got != "token tok-2-1" {
+		t.Errorf("sent with %q, error %v, want %q", got, err, "token tok-2-1")
+	}
+
+	now := time.Now()
+	for owner, want := range map[string]int{"acme": 0, "other": 4000 - rateLimitReserve} {
+		req := httptest.NewRequest(http.MethodGet, server.URL+"/repos/"+owner+"/shop", nil)
+		if got, _ := installations.budget(req, now); got != want {
+			t.Errorf("budget of %s = %d, want %d", owner, got, want)
+		}
+	}
+}
+
+func TestAppTransportTokenNotCanceledByFirstCaller(t *testing.T) {
+	transport, server := newTestAppTransport(t, 1)
+	endpoint := server.Config.Handler.(*fakeTokenEndpoint)
+	endpoint.minting = make(chan struct{}, 10)
+	endpoint.release = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := transport.token(ctx, "acme")
+		firstErr <- err
+	}()
+	<-endpoint.minting
+
+	type result struct {
+		token string
+		err   error
+	}
+	second := make(chan result)
+	go func() {
+		token, err := transport.token(context.Background(), "acme")
+		second <- result{token, err}
+	}()
+	// let the second caller join the load of the first one
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller error = %v, want context.Canceled", err)
+	}
+	close(endpoint.release)
+	if got := <-second; got.err != nil || got.token != "tok-1-1" {
+		t.Errorf("second caller token = %q, error %v, want tok-1-1", got.token, got.err)
+	}
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	if got := endpoint.minted["1"]; got != 1 {
+		t.Errorf("minted %d tokens, want 1 shared load", got)
+	}
+}
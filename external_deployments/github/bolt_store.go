@@ -0,0 +1,351 @@
+package github
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v81/github"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+// schemaVersion is increased on incompatible changes of the stored data,
+// a store with another version is cleared on open.
+// Version 2 keys the repositories by owner/name@environment and stores the time the deployments were fetched.
+const schemaVersion = 2
+
+var (
+	metaBucket        = []byte("meta")
+	schemaVersionKey  = []byte("schema_version")
+	deploymentsKey    = []byte("deployments")
+	fetchedAtKey      = []byte("fetched_at")
+	succeededAtBucket = []byte("succeeded_at")
+	comparisonsBucket = []byte("comparisons")
+)
+
+// BoltStore is a Store backed by an embedded bbolt database.
+//
+// Layout: one top level bucket per repository holding the deployments as JSON list, the time they were fetched,
+// a bucket of success times keyed by deployment ID and a bucket of comparisons keyed by base...head.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error while opening cache store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta != nil {
+			if v := meta.Get(schemaVersionKey); v != nil && binary.BigEndian.Uint64(v) == schemaVersion {
+				return nil
+			}
+		}
+
+		if meta != nil {
+			log.Infof("cache store %s has an outdated schema, clearing it", path)
+		}
+		var names [][]byte
+		if err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, name)
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+
+		meta, err := tx.CreateBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+		return meta.Put(schemaVersionKey, binary.BigEndian.AppendUint64(nil, schemaVersion))
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error while migrating cache store %s: %w", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) LoadDeployments(repo string) ([]*github.Deployment, time.Time, error) {
+	var deploys []*github.Deployment
+	var fetchedAt time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := repoBucket(tx, repo)
+		if b == nil {
+			return nil
+		}
+		v := b.Get(deploymentsKey)
+		if v == nil {
+			return nil
+		}
+		if err := fetchedAt.UnmarshalBinary(b.Get(fetchedAtKey)); err != nil {
+			return err
+		}
+		return json.Unmarshal(v, &deploys)
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error while loading deployments of %s: %w", repo, err)
+	}
+	return deploys, fetchedAt, nil
+}
+
+func (s *BoltStore) SaveDeployments(repo string, deploys []*github.Deployment, fetchedAt time.Time, deleted []*github.Deployment) error {
+	v, err := json.Marshal(deploys)
+	if err != nil {
+		return err
+	}
+	t, err := fetchedAt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := createRepoBucket(tx, repo)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(fetchedAtKey, t); err != nil {
+			return err
+		}
+		if err := b.Put(deploymentsKey, v); err != nil {
+			return err
+		}
+		return pruneDeleted(b, deploys, deleted)
+	})
+}
+
+// pruneDeleted removes the success times of the deleted deployments from the repository bucket b,
+// and the comparisons of their commits none of deploys deploys
+func pruneDeleted(b *bolt.Bucket, deploys, deleted []*github.Deployment) error {
+	if len(deleted) == 0 {
+		return nil
+	}
+	if succeededAt := b.Bucket(succeededAtBucket); succeededAt != nil {
+		for _, d := range deleted {
+			if err := succeededAt.Delete(succeededAtKey(d.GetID())); err != nil {
+				return err
+			}
+		}
+	}
+
+	comparisons := b.Bucket(comparisonsBucket)
+	if comparisons == nil {
+		return nil
+	}
+	deployed := make(map[string]bool, len(deploys))
+	for _, d := range deploys {
+		deployed[d.GetSHA()] = true
+	}
+	gone := make(map[string]bool, len(deleted))
+	for _, d := range deleted {
+		if !deployed[d.GetSHA()] {
+			gone[d.GetSHA()] = true
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+
+	// keys are collected first, bbolt does not support deleting while iterating
+	var keys [][]byte
+	if err := comparisons.ForEach(func(k, _ []byte) error {
+		base, head, _ := strings.Cut(string(k), "...")
+		if gone[base] || gone[head] {
+			keys = append(keys, k)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := comparisons.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) LoadSucceededAt(repo string) (map[int64]time.Time, error) {
+	succeededAt := make(map[int64]time.Time)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := repoBucket(tx, repo)
+		if b == nil || b.Bucket(succeededAtBucket) == nil {
+			return nil
+		}
+		return b.Bucket(succeededAtBucket).ForEach(func(k, v []byte) error {
+			var t time.Time
+			if err := t.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			succeededAt[int64(binary.BigEndian.Uint64(k))] = t
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while loading success times of %s: %w", repo, err)
+	}
+	return succeededAt, nil
+}
+
+func (s *BoltStore) SaveSucceededAt(repo string, succeededAt map[int64]time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		repoB, err := createRepoBucket(tx, repo)
+		if err != nil {
+			return err
+		}
+		b, err := repoB.CreateBucketIfNotExists(succeededAtBucket)
+		if err != nil {
+			return err
+		}
+		for id, t := range succeededAt {
+			v, err := t.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(succeededAtKey(id), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) LoadComparison(repo, base, head string) (*github.CommitsComparison, error) {
+	var cmp *github.CommitsComparison
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := repoBucket(tx, repo)
+		if b == nil || b.Bucket(comparisonsBucket) == nil {
+			return nil
+		}
+		v := b.Bucket(comparisonsBucket).Get(comparisonKey(base, head))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &cmp)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while loading comparison %s...%s of %s: %w", base, head, repo, err)
+	}
+	return cmp, nil
+}
+
+func (s *BoltStore) SaveComparison(repo, base, head string, cmp *github.CommitsComparison) error {
+	v, err := json.Marshal(cmp)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		repoB, err := createRepoBucket(tx, repo)
+		if err != nil {
+			return err
+		}
+		b, err := repoB.CreateBucketIfNotExists(comparisonsBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put(comparisonKey(base, head), v)
+	})
+}
+
+// Invalidate deletes the data of repo, including the data of its environments stored as repo@environment
+func (s *BoltStore) Invalidate(repo string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var names [][]byte
+		envPrefix := string(repoBucketName(repo)) + "@"
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if string(name) == string(repoBucketName(repo)) || strings.HasPrefix(string(name), envPrefix) {
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+var (
+	sharedStoresMu sync.Mutex
+	sharedStores   = make(map[string]*sharedBoltStore)
+)
+
+// sharedBoltStore is a BoltStore shared by the clients using the same path, bbolt locks the file for one opener.
+// It is closed once the last client closed it.
+type sharedBoltStore struct {
+	*BoltStore
+	path string
+	refs int
+}
+
+// openSharedBoltStore opens the BoltStore at path, or returns the one already opened by another client
+func openSharedBoltStore(path string) (Store, error) {
+	sharedStoresMu.Lock()
+	defer sharedStoresMu.Unlock()
+
+	if s, ok := sharedStores[path]; ok {
+		s.refs++
+		return s, nil
+	}
+	boltStore, err := NewBoltStore(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &sharedBoltStore{BoltStore: boltStore, path: path, refs: 1}
+	sharedStores[path] = s
+	return s, nil
+}
+
+func (s *sharedBoltStore) Close() error {
+	sharedStoresMu.Lock()
+	defer sharedStoresMu.Unlock()
+
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(sharedStores, s.path)
+	return s.BoltStore.Close()
+}
+
+func repoBucketName(repo string) []byte {
+	return []byte("repo/" + repo)
+}
+
+func repoBucket(tx *bolt.Tx, repo string) *bolt.Bucket {
+	return tx.Bucket(repoBucketName(repo))
+}
+
+func createRepoBucket(tx *bolt.Tx, repo string) (*bolt.Bucket, error) {
+	return tx.CreateBucketIfNotExists(repoBucketName(repo))
+}
+
+func succeededAtKey(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+func comparisonKey(base, head string) []byte {
+	return []byte(base + "..." + head)
+}
@@ -0,0 +1,177 @@
+package github
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v81/github"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+)
+
+func newTestBoltStore(t *testing.T) *BoltStore {
+	t.Helper()
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func storedDeployment(id int64, sha string) *github.Deployment {
+	return &github.Deployment{ID: github.Ptr(id), SHA: github.Ptr(sha)}
+}
+
+func TestBoltStoreSaveDeploymentsPrunesDeleted(t *testing.T) {
+	store := newTestBoltStore(t)
+	// 4 redeploys the commit of 2
+	d1, d2, d3, d4 := storedDeployment(1, "sha1"), storedDeployment(2, "sha2"), storedDeployment(3, "sha3"), storedDeployment(4, "sha2")
+	fetchedAt := baseTime
+
+	if err := store.SaveDeployments("o/r", []*github.Deployment{d4, d3, d2, d1}, fetchedAt, nil); err != nil {
+		t.Fatal(err)
+	}
+	succeededAt := map[int64]time.Time{1: baseTime, 2: baseTime.Add(time.Minute), 3: baseTime.Add(2 * time.Minute), 4: baseTime.Add(3 * time.Minute)}
+	if err := store.SaveSucceededAt("o/r", succeededAt); err != nil {
+		t.Fatal(err)
+	}
+	compared := [][2]string{{"sha1", "sha2"}, {"sha2", "sha3"}, {"sha3", "sha2"}, {"sha1", "sha3"}}
+	for _, c := range compared {
+		if err := store.SaveComparison("o/r", c[0], c[1], &github.CommitsComparison{Status: github.Ptr("ahead")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		deploys         []*github.Deployment
+		deleted         []*github.Deployment
+		wantSucceededAt []int64
+		wantCompared    [][2]string
+	}{
+		{
+			name:            "commit deployed again",
+			deploys:         []*github.Deployment{d4, d3, d1},
+			deleted:         []*github.Deployment{d2},
+			wantSucceededAt: []int64{1, 3, 4},
+			wantCompared:    compared,
+		},
+		{
+			name:            "commit not deployed anymore",
+			deploys:         []*github.Deployment{d4, d1},
+			deleted:         []*github.Deployment{d3},
+			wantSucceededAt: []int64{1, 4},
+			wantCompared:    [][2]string{{"sha1", "sha2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.SaveDeployments("o/r", tt.deploys, fetchedAt, tt.deleted); err != nil {
+				t.Fatal(err)
+			}
+
+			stored, _, err := store.LoadDeployments("o/r")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ids(stored), ids(tt.deploys); !slices.Equal(got, want) {
+				t.Errorf("stored deployments = %v, want %v", got, want)
+			}
+			got, err := store.LoadSucceededAt("o/r")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := slices.Sorted(maps.Keys(got)); !slices.Equal(ids, tt.wantSucceededAt) {
+				t.Errorf("stored success times of %v, want %v", ids, tt.wantSucceededAt)
+			}
+			for _, c := range compared {
+				cmp, err := store.LoadComparison("o/r", c[0], c[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := slices.Contains(tt.wantCompared, c); (cmp != nil) != want {
+					t.Errorf("comparison %s...%s stored = %v, want %v", c[0], c[1], cmp != nil, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBoltStoreSaveDeploymentsKeepsOtherRepositories(t *testing.T) {
+	store := newTestBoltStore(t)
+	for _, repo := range []string{"o/r", "o/other"} {
+		if err := store.SaveSucceededAt(repo, map[int64]time.Time{1: baseTime}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveComparison(repo, "sha0", "sha1", &github.CommitsComparison{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.SaveDeployments("o/r", nil, baseTime, []*github.Deployment{storedDeployment(1, "sha1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for repo, want := range map[string]bool{"o/r": false, "o/other": true} {
+		succeededAt, err := store.LoadSucceededAt(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmp, err := store.LoadComparison(repo, "sha0", "sha1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(succeededAt) == 1; got != want {
+			t.Errorf("success time of %s stored = %v, want %v", repo, got, want)
+		}
+		if got := cmp != nil; got != want {
+			t.Errorf("comparison of %s stored = %v, want %v", repo, got, want)
+		}
+	}
+}
+
+func TestRefreshPrunesStoreOfDeletedDeployments(t *testing.T) {
+	store := newTestBoltStore(t)
+	api := newFakeAPI(5)
+	client, err := NewDeploymentClientWithStore(api, &config.Config{}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListDeploymentsInRange(context.Background(), query(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmp, err := store.LoadComparison("o/r", "sha2", "sha3"); err != nil || cmp == nil {
+		t.Fatalf("comparison sha2...sha3 = %v, %v, want it stored by the first load", cmp, err)
+	}
+
+	api.delete(3)
+	if _, err := client.ListDeploymentsInRange(context.Background(), query(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	succeededAt, err := store.LoadSucceededAt("o/r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := slices.Sorted(maps.Keys(succeededAt)), []int64{1, 2, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("stored success times of %v, want %v", got, want)
+	}
+	for _, c := range [][2]string{{"sha2", "sha3"}, {"sha3", "sha4"}} {
+		cmp, err := store.LoadComparison("o/r", c[0], c[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cmp != nil {
+			t.Errorf("comparison %s...%s of the deleted deployment still stored", c[0], c[1])
+		}
+	}
+	if cmp, err := store.LoadComparison("o/r", "sha2", "sha4"); err != nil || cmp == nil {
+		t.Errorf("comparison sha2...sha4 = %v, %v, want it stored by the refresh", cmp, err)
+	}
+}
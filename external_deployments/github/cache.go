@@ -0,0 +1,144 @@
+package github
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v81/github"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// repoCache holds the deployments loaded for a single repository.
+// The cached slices are never modified in place, they are replaced on update,
+// so snapshots returned to callers stay valid without holding the lock.
+type repoCache struct {
+	mu                    sync.RWMutex
+	ghDeployments         []*github.Deployment
+	successfulDeployments []*model.Deployment
+
+	// fetchedAt is the time the deployments were first loaded, the cache is rebuilt after the TTL
+	fetchedAt time.Time
+	// hydrated is set once the cache is filled from the Store
+	hydrated bool
+	// complete is set once the whole deployment history is loaded
+	complete bool
+}
+
+// covers reports if the cached deployments reach back to since
+func (c *repoCache) covers(since time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.complete {
+		return true
+	}
+	if len(c.ghDeployments) == 0 || since.IsZero() {
+		return false
+	}
+	return c.ghDeployments[len(c.ghDeployments)-1].GetCreatedAt().Before(since)
+}
+
+func (c *repoCache) fetched() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fetchedAt
+}
+
+// expired reports if the deployments were fetched longer than ttl ago
+func (c *repoCache) expired(ttl time.Duration) bool {
+	return time.Since(c.fetched()) > ttl
+}
+
+func (c *repoCache) markComplete() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.complete = true
+}
+
+func (c *repoCache) deployments() []*github.Deployment {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ghDeployments
+}
+
+func (c *repoCache) setDeployments(deploys []*github.Deployment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ghDeployments = deploys
+}
+
+func (c *repoCache) successful() []*model.Deployment {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.successfulDeployments
+}
+
+// addSuccessful merges deploys into the successful deployments, skipping deployments
+// which were added by a concurrent load in the meantime
+func (c *repoCache) addSuccessful(deploys []*model.Deployment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.successfulDeployments = mergeSuccessful(c.successfulDeployments, deploys)
+}
+
+// removeSuccessful drops the success status of deleted deployments
+func (c *repoCache) removeSuccessful(deleted []*github.Deployment) {
+	ids := make(map[int64]bool, len(deleted))
+	for _, d := range deleted {
+		ids[d.GetID()] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.successfulDeployments = slices.DeleteFunc(slices.Clone(c.successfulDeployments), func(d *model.Deployment) bool {
+		return ids[d.ID]
+	})
+}
+
+func (c *repoCache) isHydrated() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hydrated
+}
+
+// hydrate fills the empty cache with the deployments fetched at fetchedAt and success times loaded from the Store
+func (c *repoCache) hydrate(deploys []*github.Deployment, fetchedAt time.Time, succeededAt map[int64]time.Time) {
+	successful := make([]*model.Deployment, 0, len(succeededAt))
+	for _, d := range deploys {
+		if t, ok := succeededAt[d.GetID()]; ok {
+			sd := toDeployment(d)
+			sd.SucceededAt = t
+			successful = append(successful, sd)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hydrated = true
+	if len(c.ghDeployments) > 0 {
+		return
+	}
+	if deploys == nil {
+		return
+	}
+	c.ghDeployments = deploys
+	c.fetchedAt = fetchedAt
+	c.successfulDeployments = mergeSuccessful(nil, successful)
+}
+
+// mergeSuccessful returns a new slice with the deployments of both slices sorted by SucceededAt, newest first
+func mergeSuccessful(successful, deploys []*model.Deployment) []*model.Deployment {
+	merged := slices.Clone(successful)
+	for _, d := range deploys {
+		if !slices.ContainsFunc(merged, func(deploy *model.Deployment) bool {
+			return deploy.ID == d.ID
+		}) {
+			merged = append(merged, d)
+		}
+	}
+	slices.SortFunc(merged, func(a, b *model.Deployment) int {
+		return int(b.SucceededAt.Unix() - a.SucceededAt.Unix()) // assumption: running on 64-bit or higher architecture
+	})
+	return merged
+}
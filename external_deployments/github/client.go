@@ -2,67 +2,188 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"slices"
 	"sync"
 	"time"
 
 	"github.com/google/go-github/v81/github"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/lru"
 	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
 )
 
+const (
+	defaultCacheSize      = 100
+	defaultCacheTTL       = time.Hour
+	defaultMaxConcurrency = 8
+	defaultLoadMargin     = 24 * time.Hour
+	deploymentsPerPage    = 100
+	// loadTimeout limits a load shared by coalesced callers, it is not canceled with the caller starting it
+	loadTimeout = 5 * time.Minute
+	// maxLoads limits the loads of a call whose deployments do not reach back far enough after a shared load
+	maxLoads = 3
+)
+
+// DeploymentClient is safe for concurrent use. The deployments are cached per repository,
+// concurrent identical loads of a repository are coalesced into a single set of API calls.
+//
+// The least recently used repository is evicted from the cache once more than
+// config.Config.CacheSize repositories are cached, the cache of a repository is rebuilt
+// after config.Config.CacheTTL.
 type DeploymentClient struct {
-	api                   API
-	repo                  string
-	ghDeployments         []*github.Deployment
-	successfulDeployments []*model.Deployment
+	api   API
+	store Store
+	repos *lru.Cache[repository, *repoCache]
+	loads singleflight.Group
+	// ttl is the time after which the cache of a repository is rebuilt, it also expires stored deployments
+	ttl time.Duration
+
+	// maxConcurrency limits the goroutines of a single fan-out,
+	// requests limits the concurrent fan-out requests of all queries sharing the client
+	maxConcurrency int
+	requests       *semaphore.Weighted
+
+	// loadMargin is subtracted from the start of a query when loading deployments
+	loadMargin time.Duration
+	// loadTimeout limits shared loads, including the time waiting for rate limit resets
+	loadTimeout time.Duration
+}
+
+// repository identifies the deployments of a repository by owner, name and environment,
+// an empty environment is the configured one
+type repository struct {
+	owner, name, environment string
+}
+
+func (r repository) String() string {
+	if r.environment == "" {
+		return r.owner + "/" + r.name
+	}
+	return r.owner + "/" + r.name + "@" + r.environment
+}
+
+// NewDeploymentClient creates a client persisting its cache in a BoltStore at config.Config.CachePath,
+// shared with the other clients using that path, or caching in memory only if no path is set
+func NewDeploymentClient(api API, conf *config.Config) (*DeploymentClient, error) {
+	var store Store = nopStore{}
+	if conf.CachePath != "" {
+		boltStore, err := openSharedBoltStore(conf.CachePath)
+		if err != nil {
+			return nil, err
+		}
+		store = boltStore
+	}
+	return NewDeploymentClientWithStore(api, conf, store)
 }
 
-func NewDeploymentClient(api API) (*DeploymentClient, error) {
+func NewDeploymentClientWithStore(api API, conf *config.Config, store Store) (*DeploymentClient, error) {
 	if api == nil {
 		return nil, fmt.Errorf("api cannot be nil")
 	}
+	if store == nil {
+		return nil, fmt.Errorf("store cannot be nil")
+	}
+	size := conf.CacheSize
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	ttl := conf.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	maxConcurrency := conf.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	loadMargin := conf.LoadMargin
+	if loadMargin <= 0 {
+		loadMargin = defaultLoadMargin
+	}
+	timeout := loadTimeout
+	if conf.RateLimitWait {
+		maxWait := conf.RateLimitMaxWait
+		if maxWait <= 0 {
+			maxWait = defaultMaxWait
+		}
+		timeout += maxWait
+	}
 	return &DeploymentClient{
-		api: api,
+		api:            api,
+		store:          store,
+		repos:          lru.New[repository, *repoCache](size, ttl),
+		ttl:            ttl,
+		maxConcurrency: maxConcurrency,
+		requests:       semaphore.NewWeighted(int64(maxConcurrency)),
+		loadMargin:     loadMargin,
+		loadTimeout:    timeout,
 	}, nil
 }
 
-func (gdc *DeploymentClient) ListDeployments(ctx context.Context) ([]*model.Deployment, error) {
-	err := gdc.loadDeployments(ctx)
+// InvalidateCache drops the cached and stored data of owner/repo in all environments
+func (gdc *DeploymentClient) InvalidateCache(_ context.Context, owner, repo string) error {
+	r := repository{owner: owner, name: repo}
+	gdc.repos.RemoveFunc(func(cached repository) bool {
+		return cached.owner == owner && cached.name == repo
+	})
+	if err := gdc.store.Invalidate(r.String()); err != nil {
+		return fmt.Errorf("error while invalidating cache of %s: %w", r, err)
+	}
+	return nil
+}
+
+// Close closes the underlying Store
+func (gdc *DeploymentClient) Close() error {
+	return gdc.store.Close()
+}
+
+func (gdc *DeploymentClient) ListDeployments(ctx context.Context, owner, repo string) ([]*model.Deployment, error) {
+	r := repository{owner: owner, name: repo}
+	cache := gdc.cache(r)
+	err := gdc.loadDeployments(ctx, r, cache, time.Time{})
 
 	if err != nil {
 		return nil, err
 	}
-	return toDeployments(gdc.ghDeployments), nil
+	return toDeployments(cache.deployments()), nil
 }
 
-// ListDeploymentsInRange lists deployments with a deployment status successful in range [from, to]
-func (gdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, from, to time.Time) ([]*model.Deployment, error) {
+// ListDeploymentsInRange lists deployments of q.Owner/q.Repo with a deployment status successful in range [q.From, q.To]
+func (gdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	if q.Repo == "" {
+		return nil, fmt.Errorf("no repository set in query")
+	}
+	r := repository{owner: q.Owner, name: q.Repo, environment: q.Environment}
+	from, to := q.From, q.To
 
-	err := gdc.loadSuccessfulDeploymentsInRange(ctx, from, to)
+	// the cache is resolved once, it may be evicted or expire during the call
+	cache := gdc.cache(r)
+	err := gdc.loadSuccessfulDeploymentsInRange(ctx, r, cache, from, to)
 	if err != nil {
 		return nil, err
 	}
-	successful := gdc.successfulDeployments
-
-	inRange := filterTimerangeBySucceededAt(successful, from, to)
+	successful := cache.successful()
 
-	var oneBefore *model.Deployment
-	for _, sd := range gdc.successfulDeployments {
-		if sd.SucceededAt.Before(from) {
-			oneBefore = sd
-			break
-		}
-	}
+	inRange, oneBefore := model.SplitRange(successful, from, to)
 
 	if oneBefore != nil {
 		inRange = append(inRange, oneBefore)
 	}
 
-	populated, err := gdc.populateWithCommits(ctx, inRange)
+	// the cached deployments are shared between calls, populate copies of them
+	for i, d := range inRange {
+		inRange[i] = copyDeployment(d)
+	}
+
+	populated, err := gdc.populateWithCommits(ctx, r, inRange)
 	if err != nil {
 		return nil, err
 	}
@@ -74,17 +195,31 @@ func (gdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, from, t
 	return populated, nil
 }
 
-func (gdc *DeploymentClient) SetRepo(ctx context.Context, repo string) error {
-	_, _, err := gdc.api.GetRepository(ctx, repo)
+// checkRepo returns model.ErrRepositoryNotFound if r does not exist
+func (gdc *DeploymentClient) checkRepo(ctx context.Context, r repository) error {
+	_, _, err := gdc.api.GetRepository(ctx, r.owner, r.name)
 	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", model.ErrRepositoryNotFound, r)
+		}
 		return err
 	}
-	gdc.repo = repo
 	return nil
 }
 
-func (gdc *DeploymentClient) GetRepo() string {
-	return gdc.repo
+// cache returns the cache of r, it is rebuilt once its deployments were fetched longer than the TTL ago.
+// The cache entry expires after the TTL as well, but it is younger than its deployments if they were stored.
+func (gdc *DeploymentClient) cache(r repository) *repoCache {
+	newCache := func() *repoCache {
+		return &repoCache{fetchedAt: time.Now()}
+	}
+	cache := gdc.repos.GetOrAdd(r, newCache)
+	if cache.expired(gdc.ttl) {
+		gdc.repos.Remove(r)
+		cache = gdc.repos.GetOrAdd(r, newCache)
+	}
+	return cache
 }
 
 // loadSuccessfulDeploymentsInRange
@@ -99,133 +234,335 @@ func (gdc *DeploymentClient) GetRepo() string {
 //
 // before updating cache sort the deployments by succeededAt
 // filter successful deployments in time range
-func (gdc *DeploymentClient) loadSuccessfulDeploymentsInRange(ctx context.Context, from, to time.Time) error {
-	if err := gdc.loadDeployments(ctx); err != nil {
+func (gdc *DeploymentClient) loadSuccessfulDeploymentsInRange(ctx context.Context, r repository, cache *repoCache, from, to time.Time) error {
+	key := fmt.Sprintf("successful/%s/%d/%d", r, from.UnixNano(), to.UnixNano())
+	return gdc.sharedLoad(ctx, key, func(loadCtx context.Context) error {
+		return gdc.doLoadSuccessfulDeploymentsInRange(loadCtx, r, cache, from, to)
+	})
+}
+
+// sharedLoad runs load once for concurrent callers with the same key. The load is not canceled with the
+// context of the caller starting it, so the other callers are not failed by it, but limited by loadTimeout.
+// Each caller stops waiting once its own ctx is done.
+func (gdc *DeploymentClient) sharedLoad(ctx context.Context, key string, load func(ctx context.Context) error) error {
+	ch := gdc.loads.DoChan(key, func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), gdc.loadTimeout)
+		defer cancel()
+		return nil, load(loadCtx)
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (gdc *DeploymentClient) doLoadSuccessfulDeploymentsInRange(ctx context.Context, r repository, cache *repoCache, from, to time.Time) error {
+	// deployments created before from might still succeed in [from, to]
+	since := from.Add(-gdc.loadMargin)
+	if err := gdc.loadDeployments(ctx, r, cache, since); err != nil {
 		return err
 	}
 
-	allDeploys := toDeployments(gdc.ghDeployments)
+	allDeploys := toDeployments(cache.deployments())
 
 	// load successful deployments in Range
 	possibleSuccessfulDeploys := filterTimerangeBySuccessPossible(allDeploys, from, to)
 
 	// only refresh success status if not already succeeded
+	successful := cache.successful()
 	newPossibleSuccessfulDeploys := make([]*model.Deployment, 0, len(possibleSuccessfulDeploys))
 	for _, possibleDeploy := range possibleSuccessfulDeploys {
 
-		if !slices.ContainsFunc(gdc.successfulDeployments, func(deploy *model.Deployment) bool {
+		if !slices.ContainsFunc(successful, func(deploy *model.Deployment) bool {
 			return deploy.ID == possibleDeploy.ID
 		}) {
 			newPossibleSuccessfulDeploys = append(newPossibleSuccessfulDeploys, possibleDeploy)
 		}
 	}
 
-	populated, err := gdc.populateSuccessStatus(ctx, newPossibleSuccessfulDeploys)
+	populated, err := gdc.populateSuccessStatus(ctx, r, newPossibleSuccessfulDeploys)
 	if err != nil {
 		return err
 	}
 
-	newSuccessfulDeploys := append(gdc.successfulDeployments, populated...)
-	slices.SortFunc(newSuccessfulDeploys, func(a, b *model.Deployment) int {
-		return int(b.SucceededAt.Unix() - a.SucceededAt.Unix()) // assumption: running on 64-bit or higher architecture
-	})
+	cache.addSuccessful(populated)
 
-	gdc.successfulDeployments = newSuccessfulDeploys
+	succeededAt := make(map[int64]time.Time, len(populated))
+	for _, d := range populated {
+		succeededAt[d.ID] = d.SucceededAt
+	}
+	if err := gdc.store.SaveSucceededAt(r.String(), succeededAt); err != nil {
+		log.Printf("WARN error while storing success times of %s: %v\n", r, err)
+	}
 	return nil
 }
 
-// loadDeployments loads all deployments on the first time and stores them in cache
-func (gdc *DeploymentClient) loadDeployments(ctx context.Context) error {
-	if len(gdc.ghDeployments) > 0 {
-		prevNewestID := gdc.ghDeployments[0].GetID()
+// loadDeployments refreshes cache, the cache of r, and makes sure it reaches back to since.
+// A zero since loads the whole history. Concurrent loads of the same repository share a single load,
+// a caller needing older deployments than the shared load fetched, or whose cache was replaced in the
+// meantime, loads again, up to maxLoads times.
+func (gdc *DeploymentClient) loadDeployments(ctx context.Context, r repository, cache *repoCache, since time.Time) error {
+	for range maxLoads {
+		err := gdc.sharedLoad(ctx, "deployments/"+r.String(), func(loadCtx context.Context) error {
+			return gdc.doLoadDeployments(loadCtx, r, cache, since)
+		})
+		if err != nil {
+			return err
+		}
+		if cache.covers(since) {
+			return nil
+		}
+	}
+	return fmt.Errorf("deployments of %s do not reach back to %v after %d loads", r, since, maxLoads)
+}
 
-		newDeployCount := -1
-		var allDeploys []*github.Deployment
+func (gdc *DeploymentClient) doLoadDeployments(ctx context.Context, r repository, cache *repoCache, since time.Time) error {
+	if !cache.isHydrated() {
+		gdc.hydrate(r, cache)
+	}
 
-		perPage := 100
-		opts := &github.DeploymentsListOptions{
-			ListOptions: github.ListOptions{Page: 1, PerPage: perPage},
+	if len(cache.deployments()) > 0 {
+		if err := gdc.refreshDeployments(ctx, r, cache); err != nil {
+			return err
 		}
+	} else if err := gdc.checkRepo(ctx, r); err != nil {
+		return err
+	}
 
-		for opts.ListOptions.Page > 0 && newDeployCount == -1 {
-			deploys, resp, err := gdc.api.ListDeployments(ctx, gdc.repo, opts)
-			if err != nil {
-				return fmt.Errorf("error while fetching github ghDeployments: %w", err)
-			}
+	if cache.covers(since) {
+		return nil
+	}
+	return gdc.extendDeployments(ctx, r, cache, since)
+}
 
-			// search until previous new deployment found
-			for i, deploy := range deploys {
-				if deploy.GetID() == prevNewestID {
-					newDeployCount = i + (opts.ListOptions.Page-1)*perPage
-					break
-				}
-			}
-			allDeploys = append(allDeploys, deploys...)
+// refreshDeployments prepends the deployments created since the last load to the cache.
+//
+// The pages are listed until one holds a cached deployment. The listing replaces the cached deployments
+// down to the oldest one listed again: cached deployments of that window which are not listed anymore
+// were deleted and are dropped with their success status. If the whole history was listed, it replaces
+// the whole cache.
+func (gdc *DeploymentClient) refreshDeployments(ctx context.Context, r repository, cache *repoCache) error {
+	cached := cache.deployments()
+	cachedIndex := make(map[int64]int, len(cached))
+	for i, d := range cached {
+		cachedIndex[d.GetID()] = i
+	}
 
-			if resp.Rate.Remaining <= 10 {
-				return fmt.Errorf("rate limit nearly exhausted, only 10 calls remaining; resets at %v",
-					resp.Rate.Reset)
-			}
+	// deepest is the position in cached of the oldest cached deployment listed again
+	deepest := -1
+	var listed []*github.Deployment
+	seen := make(map[int64]bool)
 
-			opts.ListOptions.Page = resp.NextPage
-		}
+	opts := &github.DeploymentsListOptions{
+		Environment: r.environment,
+		ListOptions: github.ListOptions{Page: 1, PerPage: deploymentsPerPage},
+	}
 
-		if newDeployCount == 0 {
-			return nil
+	for opts.ListOptions.Page > 0 && deepest == -1 {
+		deploys, resp, err := gdc.api.ListDeployments(ctx, r.owner, r.name, opts)
+		if err != nil {
+			return fmt.Errorf("error while fetching github ghDeployments: %w", err)
 		}
 
-		if newDeployCount > 0 {
-			// assumption deployments from API are sorted by creation date in descending oder
-			newDeploys := allDeploys[:newDeployCount]
-			gdc.ghDeployments = append(newDeploys, gdc.ghDeployments...)
-			return nil
+		for _, deploy := range deploys {
+			// deployments created while paging push the listed ones to the next page
+			if seen[deploy.GetID()] {
+				continue
+			}
+			seen[deploy.GetID()] = true
+			listed = append(listed, deploy)
+			if j, ok := cachedIndex[deploy.GetID()]; ok {
+				deepest = max(deepest, j)
+			}
 		}
-		return fmt.Errorf("Could not find cached deployment %d\n", prevNewestID)
+
+		opts.ListOptions.Page = resp.NextPage
+	}
+	exhausted := opts.ListOptions.Page == 0
+
+	window := cached
+	if !exhausted {
+		window = cached[:deepest+1]
+	}
+	if deepest == -1 {
+		log.Printf("WARN no cached deployment of %s is listed anymore, rebuilding cache\n", r)
+	}
+	deleted := gdc.dropDeleted(r, cache, window, seen)
+
+	// assumption deployments from API are sorted by creation date in descending oder
+	refreshed := append(slices.Clip(listed), cached[len(window):]...)
+	if !slices.EqualFunc(refreshed, cached, func(a, b *github.Deployment) bool { return a.GetID() == b.GetID() }) {
+		gdc.setDeployments(r, cache, refreshed, deleted)
+	}
+	if exhausted {
+		cache.markComplete()
+	}
+	return nil
+}
+
+// extendDeployments appends older deployments to the cache until the page holding since is loaded
+// or the history is complete.
+//
+// GitHub lists deployments by page only, so the oldest cached deployment serves as cursor: loading starts
+// at the page which held it when it was loaded and steps back while the page lists no cached deployment,
+// as deletions shift the deployments to earlier pages. The listing replaces the cached deployments from
+// the newest one listed again, cached deployments of that window which are not listed anymore were deleted.
+func (gdc *DeploymentClient) extendDeployments(ctx context.Context, r repository, cache *repoCache, since time.Time) error {
+	cached := cache.deployments()
+	cachedIndex := make(map[int64]int, len(cached))
+	for i, d := range cached {
+		cachedIndex[d.GetID()] = i
+	}
+	isCached := func(d *github.Deployment) bool {
+		_, ok := cachedIndex[d.GetID()]
+		return ok
 	}
 
-	var allDeploys []*github.Deployment
+	// first is the position in cached of the newest cached deployment listed again
+	first := len(cached)
+	var listed []*github.Deployment
+	seen := make(map[int64]bool)
+	found, complete := false, false
 	opts := &github.DeploymentsListOptions{
-		ListOptions: github.ListOptions{Page: 1},
+		Environment: r.environment,
+		ListOptions: github.ListOptions{Page: (len(cached)-1)/deploymentsPerPage + 1, PerPage: deploymentsPerPage},
 	}
 
-	for opts.ListOptions.Page > 0 {
-		deploys, resp, err := gdc.api.ListDeployments(ctx, gdc.repo, opts)
+	for {
+		deploys, resp, err := gdc.api.ListDeployments(ctx, r.owner, r.name, opts)
 		if err != nil {
 			return fmt.Errorf("error while fetching github ghDeployments: %w", err)
 		}
 
-		allDeploys = append(allDeploys, deploys...)
+		if !found && opts.ListOptions.Page > 1 && !slices.ContainsFunc(deploys, isCached) {
+			opts.ListOptions.Page--
+			continue
+		}
+		found = true
 
-		if resp.Rate.Remaining <= 10 {
-			return fmt.Errorf("rate limit nearly exhausted, only 10 calls remaining; resets at %v",
-				resp.Rate.Reset)
+		for _, deploy := range deploys {
+			if seen[deploy.GetID()] {
+				continue
+			}
+			seen[deploy.GetID()] = true
+			listed = append(listed, deploy)
+			if j, ok := cachedIndex[deploy.GetID()]; ok {
+				first = min(first, j)
+			}
 		}
 
+		if resp.NextPage == 0 {
+			complete = true
+			break
+		}
+		// assumption deployments from API are sorted by creation date in descending oder
+		if len(deploys) > 0 && deploys[len(deploys)-1].GetCreatedAt().Before(since) {
+			break
+		}
 		opts.ListOptions.Page = resp.NextPage
 	}
 
-	gdc.ghDeployments = allDeploys
+	deleted := gdc.dropDeleted(r, cache, cached[first:], seen)
+	log.Printf("TRACE loaded %d older deployments of %s, complete: %v\n", len(listed)-(len(cached)-first-len(deleted)), r, complete)
+	gdc.setDeployments(r, cache, append(slices.Clip(cached[:first]), listed...), deleted)
+	if complete {
+		cache.markComplete()
+	}
 	return nil
 }
 
-func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, deployments []*model.Deployment) ([]*model.Deployment, error) {
+// dropDeleted drops the success status of the deployments of window which are not listed anymore,
+// it returns them
+func (gdc *DeploymentClient) dropDeleted(r repository, cache *repoCache, window []*github.Deployment, listed map[int64]bool) []*github.Deployment {
+	deleted := slices.DeleteFunc(slices.Clone(window), func(d *github.Deployment) bool {
+		return listed[d.GetID()]
+	})
+	if len(deleted) > 0 {
+		log.Printf("WARN %d cached deployments of %s were deleted\n", len(deleted), r)
+		cache.removeSuccessful(deleted)
+	}
+	return deleted
+}
+
+// setDeployments updates the cached deployments and persists them, dropping the stored data of the deleted ones
+func (gdc *DeploymentClient) setDeployments(r repository, cache *repoCache, deploys, deleted []*github.Deployment) {
+	cache.setDeployments(deploys)
+	if err := gdc.store.SaveDeployments(r.String(), deploys, cache.fetched(), deleted); err != nil {
+		log.Printf("WARN error while storing deployments of %s: %v\n", r, err)
+	}
+}
+
+// hydrate fills cache with the data stored for r, errors of the store are logged and ignored
+func (gdc *DeploymentClient) hydrate(r repository, cache *repoCache) {
+	deploys, fetchedAt, err := gdc.store.LoadDeployments(r.String())
+	if err != nil {
+		log.Printf("WARN %v\n", err)
+	}
+	if deploys != nil && time.Since(fetchedAt) > gdc.ttl {
+		log.Printf("TRACE stored deployments of %s fetched at %v expired, rebuilding cache\n", r, fetchedAt)
+		deploys = nil
+	}
+	succeededAt, err := gdc.store.LoadSucceededAt(r.String())
+	if err != nil {
+		log.Printf("WARN %v\n", err)
+	}
+	cache.hydrate(deploys, fetchedAt, succeededAt)
+}
+
+// compareCommits compares base...head, using the stored comparison if present.
+// Comparisons of two commits never change, so they are stored without expiry.
+func (gdc *DeploymentClient) compareCommits(ctx context.Context, r repository, base, head string, opts *github.ListOptions) (*github.CommitsComparison, error) {
+	cmp, err := gdc.store.LoadComparison(r.String(), base, head)
+	if err != nil {
+		log.Printf("WARN %v\n", err)
+	}
+	if cmp != nil {
+		return cmp, nil
+	}
+
+	cmp, err = gdc.api.CompareCommits(ctx, r.owner, r.name, base, head, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := gdc.store.SaveComparison(r.String(), base, head, cmp); err != nil {
+		log.Printf("WARN error while storing comparison %s...%s of %s: %v\n", base, head, r, err)
+	}
+	return cmp, nil
+}
+
+// PopulateWithCommits sets the commits added and removed by each deployment of owner/repo compared to the
+// next one in deployments, which has to be sorted by SucceededAt, newest first. It lets providers which
+// only know the deployed revisions, like Argo CD, use the GitHub comparisons and their cache.
+func (gdc *DeploymentClient) PopulateWithCommits(ctx context.Context, owner, repo string, deployments []*model.Deployment) ([]*model.Deployment, error) {
+	return gdc.populateWithCommits(ctx, repository{owner: owner, name: repo}, deployments)
+}
+
+func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, r repository, deployments []*model.Deployment) ([]*model.Deployment, error) {
 	if len(deployments) <= 1 {
 		return deployments, nil
 	}
 
 	// Create an errgroup with a derived context that cancels if any goroutine errors out.
 	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(gdc.maxConcurrency)
 	start := time.Now()
 
 	for i := range len(deployments) - 1 {
 
 		g.Go(func() error {
+			if err := gdc.requests.Acquire(gCtx, 1); err != nil {
+				return err
+			}
+			defer gdc.requests.Release(1)
+
 			d := deployments[i]
 			head := deployments[i].SHA
 			base := deployments[i+1].SHA
 
 			// Use the gCtx so this request cancels if another goroutine fails
-			commitCmp, err := gdc.api.CompareCommits(gCtx, gdc.repo, base, head, nil)
+			commitCmp, err := gdc.compareCommits(gCtx, r, base, head, nil)
 			if err != nil {
 				return fmt.Errorf("error while comparing commits: %w", err)
 			}
@@ -237,7 +574,7 @@ func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, deployment
 				d.Added = toCommits(commitCmp)
 
 			case "behind":
-				behindCmp, err := gdc.api.CompareCommits(gCtx, gdc.repo, head, base, &github.ListOptions{})
+				behindCmp, err := gdc.compareCommits(gCtx, r, head, base, &github.ListOptions{})
 				if err != nil {
 					return fmt.Errorf("error comparing behind commits: %w", err)
 				}
@@ -246,7 +583,7 @@ func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, deployment
 			case "diverged":
 				d.Added = toCommits(commitCmp)
 				mergeBase := commitCmp.GetMergeBaseCommit().GetSHA()
-				divergedCmp, err := gdc.api.CompareCommits(gCtx, gdc.repo, mergeBase, base, &github.ListOptions{})
+				divergedCmp, err := gdc.compareCommits(gCtx, r, mergeBase, base, &github.ListOptions{})
 				if err != nil {
 					return fmt.Errorf("error comparing diverged commits: %w", err)
 				}
@@ -276,16 +613,6 @@ func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, deployment
 	return deployments, nil
 }
 
-func filterTimerangeBySucceededAt(deployments []*model.Deployment, from time.Time, to time.Time) []*model.Deployment {
-	filtered := make([]*model.Deployment, 0, len(deployments))
-	for _, d := range deployments {
-		if d.SucceededAt.After(from) && d.SucceededAt.Before(to) {
-			filtered = append(filtered, d)
-		}
-	}
-	return filtered
-}
-
 // filterTimerangeBySuccessPossible filters deployments which could have a succeeded in the timeframe
 func filterTimerangeBySuccessPossible(deployments []*model.Deployment, from time.Time, to time.Time) []*model.Deployment {
 	filtered := make([]*model.Deployment, 0, len(deployments))
@@ -298,30 +625,32 @@ func filterTimerangeBySuccessPossible(deployments []*model.Deployment, from time
 }
 
 // populateSuccessStatus assumption: deployment status states: x -> success -> inactive
-func (gdc *DeploymentClient) populateSuccessStatus(ctx context.Context, deploys []*model.Deployment) ([]*model.Deployment, error) {
+func (gdc *DeploymentClient) populateSuccessStatus(ctx context.Context, r repository, deploys []*model.Deployment) ([]*model.Deployment, error) {
 	successful := make([]*model.Deployment, 0, len(deploys))
 	var mu sync.Mutex
 	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(gdc.maxConcurrency)
 
 	start := time.Now()
 
 	for _, d := range deploys {
 		g.Go(func() error {
+			if err := gdc.requests.Acquire(gCtx, 1); err != nil {
+				return err
+			}
+			defer gdc.requests.Release(1)
+
 			opts := &github.ListOptions{
 				Page: 1,
 			}
 		out:
 			for opts.Page > 0 {
-				statuses, resp, err := gdc.api.ListDeploymentStatuses(gCtx, gdc.repo, d.ID, opts)
+				statuses, resp, err := gdc.api.ListDeploymentStatuses(gCtx, r.owner, r.name, d.ID, opts)
 				if err != nil {
 					return fmt.Errorf("failed to get deployment statuses for %d: %w", d.ID, err)
 				}
 				opts.Page = resp.NextPage
 
-				if resp.Rate.Remaining <= 10 {
-					return fmt.Errorf("rate limit nearly exhausted, only 10 calls remaining; resets at %v", resp.Rate.Reset)
-				}
-
 				for _, status := range statuses {
 					if status.GetState() == "success" {
 						d.SucceededAt = status.GetUpdatedAt().Time
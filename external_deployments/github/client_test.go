@@ -0,0 +1,485 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v81/github"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+var baseTime = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+// fakeAPI serves deployments with IDs 1..n, created a minute apart and succeeded 30 seconds after creation
+type fakeAPI struct {
+	mu          sync.Mutex
+	deployments []*github.Deployment // newest first
+	calls       map[string]int
+
+	// started receives a value when ListDeployments is called, release blocks it until closed, if set
+	started chan struct{}
+	release chan struct{}
+	// listed is called by ListDeployments, if set
+	listed func()
+
+	// delay is the duration of status and comparison requests, inFlight counts them
+	delay                 time.Duration
+	inFlight, maxInFlight int
+}
+
+func newFakeAPI(n int) *fakeAPI {
+	api := &fakeAPI{calls: make(map[string]int)}
+	for id := int64(n); id >= 1; id-- {
+		created := baseTime.Add(time.Duration(id) * time.Minute)
+		api.deployments = append(api.deployments, &github.Deployment{
+			ID:        github.Ptr(id),
+			SHA:       github.Ptr(fmt.Sprintf("sha%d", id)),
+			CreatedAt: &github.Timestamp{Time: created},
+			UpdatedAt: &github.Timestamp{Time: created.Add(30 * time.Second)},
+		})
+	}
+	return api
+}
+
+// delete removes the deployments with ids like a user deleting them on GitHub
+func (f *fakeAPI) delete(ids ...int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deployments = slices.DeleteFunc(f.deployments, func(d *github.Deployment) bool {
+		return slices.Contains(ids, d.GetID())
+	})
+}
+
+// add creates the deployment id as newest deployment
+func (f *fakeAPI) add(id int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	created := baseTime.Add(time.Duration(id) * time.Minute)
+	f.deployments = append([]*github.Deployment{{
+		ID:        github.Ptr(id),
+		SHA:       github.Ptr(fmt.Sprintf("sha%d", id)),
+		CreatedAt: &github.Timestamp{Time: created},
+		UpdatedAt: &github.Timestamp{Time: created.Add(30 * time.Second)},
+	}}, f.deployments...)
+}
+
+func (f *fakeAPI) count(call string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[call]
+}
+
+func (f *fakeAPI) enter(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[call]++
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+}
+
+func (f *fakeAPI) leave() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+}
+
+func (f *fakeAPI) GetRepository(_ context.Context, owner, repoName string) (*github.Repository, *github.Response, error) {
+	return &github.Repository{FullName: github.Ptr(owner + "/" + repoName)}, &github.Response{}, nil
+}
+
+func (f *fakeAPI) ListDeployments(ctx context.Context, _, _ string, opts *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error) {
+	if f.started != nil {
+		f.started <- struct{}{}
+	}
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	if f.listed != nil {
+		f.listed()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["ListDeployments"]++
+
+	page := max(opts.Page, 1)
+	start := min((page-1)*opts.PerPage, len(f.deployments))
+	end := min(start+opts.PerPage, len(f.deployments))
+	resp := &github.Response{}
+	if end < len(f.deployments) {
+		resp.NextPage = page + 1
+	}
+	return slices.Clone(f.deployments[start:end]), resp, nil
+}
+
+func (f *fakeAPI) ListDeploymentStatuses(ctx context.Context, _, _ string, id int64, _ *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error) {
+	f.enter("ListDeploymentStatuses")
+	defer f.leave()
+	if err := sleep(ctx, f.delay); err != nil {
+		return nil, nil, err
+	}
+
+	succeeded := baseTime.Add(time.Duration(id)*time.Minute + 30*time.Second)
+	return []*github.DeploymentStatus{{
+		State:     github.Ptr("success"),
+		UpdatedAt: &github.Timestamp{Time: succeeded},
+	}}, &github.Response{}, nil
+}
+
+func (f *fakeAPI) CompareCommits(ctx context.Context, _, _, base, head string, _ *github.ListOptions) (*github.CommitsComparison, error) {
+	f.enter("CompareCommits")
+	defer f.leave()
+	if err := sleep(ctx, f.delay); err != nil {
+		return nil, err
+	}
+
+	return &github.CommitsComparison{
+		HTMLURL:      github.Ptr("https://github.com/o/r/compare/" + base + "..." + head),
+		Status:       github.Ptr("ahead"),
+		TotalCommits: github.Ptr(1),
+		Commits: []*github.RepositoryCommit{{
+			SHA:    github.Ptr(head),
+			Commit: &github.Commit{Message: github.Ptr("commit " + head)},
+		}},
+	}, nil
+}
+
+func newTestClient(t *testing.T, api API, conf *config.Config) *DeploymentClient {
+	t.Helper()
+	client, err := NewDeploymentClientWithStore(api, conf, nopStore{})
+	if err != nil {
+		t.Fatalf("NewDeploymentClientWithStore() error = %v", err)
+	}
+	return client
+}
+
+// query selects the deployments which succeeded between the creation of deployment fromID and toID
+func query(fromID, toID int64) models.DeploymentsQuery {
+	return models.DeploymentsQuery{
+		Owner: "o",
+		Repo:  "r",
+		From:  baseTime.Add(time.Duration(fromID) * time.Minute),
+		To:    baseTime.Add(time.Duration(toID) * time.Minute),
+	}
+}
+
+func ids(deployments []*github.Deployment) []int64 {
+	result := make([]int64, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.GetID()
+	}
+	return result
+}
+
+func TestSharedLoadNotCanceledByFirstCaller(t *testing.T) {
+	api := newFakeAPI(5)
+	api.started = make(chan struct{}, 10)
+	api.release = make(chan struct{})
+	client := newTestClient(t, api, &config.Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := client.ListDeploymentsInRange(ctx, query(0, 10))
+		firstErr <- err
+	}()
+	<-api.started
+
+	secondErr := make(chan error)
+	go func() {
+		_, err := client.ListDeploymentsInRange(context.Background(), query(0, 10))
+		secondErr <- err
+	}()
+	// let the second caller join the load of the first one
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller error = %v, want context.Canceled", err)
+	}
+	close(api.release)
+	if err := <-secondErr; err != nil {
+		t.Fatalf("second caller error = %v, want nil", err)
+	}
+	if got := api.count("ListDeployments"); got != 1 {
+		t.Errorf("ListDeployments calls = %d, want 1 shared load", got)
+	}
+}
+
+// TestConcurrentListDeploymentsInRange is meant to run with -race, it queries several repositories
+// and time ranges concurrently through the mock while invalidating caches
+func TestConcurrentListDeploymentsInRange(t *testing.T) {
+	t.Setenv("MOCK_DEPLOYMENTS_LENGTH", "5")
+	client := newTestClient(t, NewMockAPI(), &config.Config{MaxConcurrency: 32})
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := range 12 {
+		wg.Go(func() {
+			q := models.DeploymentsQuery{
+				Owner: "mock-owner",
+				Repo:  fmt.Sprintf("repo-%d", i%3),
+				From:  now.Add(-time.Duration(5+i%2*5) * time.Minute),
+				To:    now,
+			}
+			deployments, err := client.ListDeploymentsInRange(context.Background(), q)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !slices.IsSortedFunc(deployments, func(a, b *model.Deployment) int {
+				return b.SucceededAt.Compare(a.SucceededAt)
+			}) {
+				errs <- fmt.Errorf("deployments of %s not sorted newest first", q.Repo)
+			}
+		})
+	}
+	for i := range 3 {
+		wg.Go(func() {
+			if err := client.InvalidateCache(context.Background(), "mock-owner", fmt.Sprintf("repo-%d", i)); err != nil {
+				errs <- err
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestMaxConcurrencyBoundsRequests(t *testing.T) {
+	api := newFakeAPI(40)
+	api.delay = 10 * time.Millisecond
+	client := newTestClient(t, api, &config.Config{MaxConcurrency: 3})
+
+	// the queries share the limit of the client
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			if _, err := client.ListDeploymentsInRange(context.Background(), query(int64(i), 41)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if api.maxInFlight > 3 {
+		t.Errorf("max concurrent requests = %d, want at most 3", api.maxInFlight)
+	}
+	if api.maxInFlight < 2 {
+		t.Errorf("max concurrent requests = %d, want requests to run concurrently", api.maxInFlight)
+	}
+	if api.count("CompareCommits") == 0 || api.count("ListDeploymentStatuses") == 0 {
+		t.Errorf("calls = %v, want status and comparison requests", api.calls)
+	}
+}
+
+func TestLoadKeepsCacheEvictedDuringCall(t *testing.T) {
+	api := newFakeAPI(5)
+	client := newTestClient(t, api, &config.Config{CacheSize: 1})
+	// caching another repository evicts o/r while its deployments are listed
+	api.listed = func() {
+		client.cache(repository{owner: "o", name: "other"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	deployments, err := client.ListDeploymentsInRange(ctx, query(0, 10))
+	if err != nil {
+		t.Fatalf("ListDeploymentsInRange() error = %v", err)
+	}
+	if got, want := deploymentIDs(deployments), []int64{5, 4, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+	if got := api.count("ListDeployments"); got != 1 {
+		t.Errorf("ListDeployments calls = %d, want 1", got)
+	}
+}
+
+func cachedIDs(client *DeploymentClient) []int64 {
+	return ids(client.cache(repository{owner: "o", name: "r"}).deployments())
+}
+
+func deploymentIDs(deployments []*model.Deployment) []int64 {
+	result := make([]int64, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.ID
+	}
+	return result
+}
+
+func TestRefreshDropsDeletedNewestDeployments(t *testing.T) {
+	api := newFakeAPI(5)
+	client := newTestClient(t, api, &config.Config{})
+	if _, err := client.ListDeploymentsInRange(context.Background(), query(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	api.delete(5, 4)
+	api.add(6)
+	deployments, err := client.ListDeploymentsInRange(context.Background(), query(0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := cachedIDs(client), []int64{6, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("cached deployments = %v, want %v", got, want)
+	}
+	if got, want := deploymentIDs(deployments), []int64{6, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+}
+
+func TestRefreshRebuildsCacheIfNoCachedDeploymentIsListed(t *testing.T) {
+	api := newFakeAPI(3)
+	client := newTestClient(t, api, &config.Config{})
+	if _, err := client.ListDeploymentsInRange(context.Background(), query(0, 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	api.delete(1, 2, 3)
+	api.add(10)
+	api.add(11)
+	deployments, err := client.ListDeploymentsInRange(context.Background(), query(0, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := cachedIDs(client), []int64{11, 10}; !slices.Equal(got, want) {
+		t.Errorf("cached deployments = %v, want %v", got, want)
+	}
+	if got, want := deploymentIDs(deployments), []int64{11, 10}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+}
+
+// idRange returns the ids from down to to
+func idRange(from, to int64, except ...int64) []int64 {
+	var result []int64
+	for id := from; id >= to; id-- {
+		if !slices.Contains(except, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func TestRefreshDropsDeletedDeploymentBelowNewest(t *testing.T) {
+	api := newFakeAPI(250)
+	client := newTestClient(t, api, &config.Config{})
+	if _, err := client.ListDeploymentsInRange(context.Background(), query(0, 251)); err != nil {
+		t.Fatal(err)
+	}
+
+	api.delete(240)
+	deployments, err := client.ListDeploymentsInRange(context.Background(), query(0, 251))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := idRange(250, 1, 240)
+	if got := cachedIDs(client); !slices.Equal(got, want) {
+		t.Errorf("cached deployments = %v, want %v", got, want)
+	}
+	if got := deploymentIDs(deployments); !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+}
+
+func TestExtendReconcilesShiftedPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted []int64
+	}{
+		{name: "deleted on the page of the oldest cached deployment", deleted: idRange(64, 60)},
+		{name: "page of the oldest cached deployment deleted", deleted: idRange(150, 51)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(250)
+			client := newTestClient(t, api, &config.Config{LoadMargin: time.Minute})
+			// loads the pages of 250..151 and 150..51
+			if _, err := client.ListDeploymentsInRange(context.Background(), query(110, 251)); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := cachedIDs(client), idRange(250, 51); !slices.Equal(got, want) {
+				t.Fatalf("cached deployments = %v, want %v", got, want)
+			}
+
+			api.delete(tt.deleted...)
+			deployments, err := client.ListDeploymentsInRange(context.Background(), query(0, 251))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := idRange(250, 1, tt.deleted...)
+			if got := cachedIDs(client); !slices.Equal(got, want) {
+				t.Errorf("cached deployments = %v, want %v", got, want)
+			}
+			if got := deploymentIDs(deployments); !slices.Equal(got, want) {
+				t.Errorf("deployments = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStoredDeploymentsExpireAfterCacheTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		expired bool
+	}{
+		{name: "fresh", age: 10 * time.Minute},
+		{name: "expired", age: 2 * time.Hour, expired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewBoltStore(filepath.Join(t.TempDir(), "cache.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			api := newFakeAPI(3)
+			fetchedAt := time.Now().Add(-tt.age).Truncate(time.Second)
+			if err := store.SaveDeployments("o/r", api.deployments[1:], fetchedAt, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := NewDeploymentClientWithStore(api, &config.Config{CacheTTL: time.Hour}, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.ListDeploymentsInRange(context.Background(), query(0, 10)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := client.cache(repository{owner: "o", name: "r"}).fetched()
+			if got.Equal(fetchedAt) == tt.expired {
+				t.Errorf("cache fetched at %v, stored deployments fetched at %v, want expired %v", got, fetchedAt, tt.expired)
+			}
+			_, stored, err := store.LoadDeployments("o/r")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !stored.Equal(got) {
+				t.Errorf("stored fetched at %v, want %v", stored, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package github
+
+import (
+	"bytes"
+	"expvar"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/lru"
+)
+
+const (
+	conditionalCacheSize = 10000
+	// conditionalCacheBytes bounds the remembered responses by the size of their bodies and headers
+	conditionalCacheBytes = 64 << 20
+	// conditionalCacheTTL drops remembered responses after a day, the next request of their URL is a plain miss
+	conditionalCacheTTL = 24 * time.Hour
+)
+
+var (
+	conditionalHits   = expvar.NewInt("github_conditional_hits")
+	conditionalMisses = expvar.NewInt("github_conditional_misses")
+)
+
+// ConditionalTransport remembers the ETag and Last-Modified headers of GET responses and sends
+// If-None-Match and If-Modified-Since on later requests of the same URL.
+// GitHub does not count 304 Not Modified responses against the rate limit,
+// the transport answers them with the remembered response.
+//
+// The hit and miss counts of all transports are published with expvar as
+// github_conditional_hits and github_conditional_misses.
+type ConditionalTransport struct {
+	base      http.RoundTripper
+	responses *lru.Cache[string, *conditionalResponse]
+
+	hits, misses atomic.Int64
+}
+
+type conditionalResponse struct {
+	etag, lastModified string
+	header             http.Header
+	body               []byte
+}
+
+// size returns the approximate memory held by the response
+func (r *conditionalResponse) size() int64 {
+	n := len(r.etag) + len(r.lastModified) + len(r.body)
+	for k, values := range r.header {
+		n += len(k)
+		for _, v := range values {
+			n += len(v)
+		}
+	}
+	return int64(n)
+}
+
+// ConditionalStats counts the GET requests answered from the cache (Hits) and by the API (Misses)
+type ConditionalStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+func NewConditionalTransport(base http.RoundTripper) *ConditionalTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &ConditionalTransport{
+		base:      base,
+		responses: lru.NewWithCost[string, *conditionalResponse](conditionalCacheSize, conditionalCacheTTL, conditionalCacheBytes, (*conditionalResponse).size),
+	}
+}
+
+func (t *ConditionalTransport) Stats() ConditionalStats {
+	return ConditionalStats{
+		Hits:   t.hits.Load(),
+		Misses: t.misses.Load(),
+	}
+}
+
+func (t *ConditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	key := conditionalKey(req)
+	cached, ok := t.responses.Get(key)
+	if ok {
+		req = req.Clone(req.Context())
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		t.hits.Add(1)
+		conditionalHits.Add(1)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		// keep the current headers of the 304, e.g. the rate limit, on top of the remembered ones
+		header := cached.header.Clone()
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = http.StatusText(http.StatusOK)
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(cached.body))
+		resp.ContentLength = int64(len(cached.body))
+		return resp, nil
+	}
+
+	t.misses.Add(1)
+	conditionalMisses.Add(1)
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	t.responses.Add(key, &conditionalResponse{
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header.Clone(),
+		body:         body,
+	})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// conditionalKey identifies the response of req by its URL. The transport runs before a credential
+// of the pool is chosen, so responses are shared by all credentials: GitHub validates the ETag against the
+// response for the credential the request is sent with and only answers 304 if that response is unchanged.
+func conditionalKey(req *http.Request) string {
+	return req.URL.String()
+}
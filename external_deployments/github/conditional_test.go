@@ -0,0 +1,56 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// credentialTransport sets the Authorization header like a credential of the pool below the ConditionalTransport
+type credentialTransport struct {
+	token string
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestConditionalTransportRevalidatesWithEachCredential(t *testing.T) {
+	var validatedWith []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			validatedWith = append(validatedWith, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, "deployments")
+	}))
+	defer server.Close()
+
+	credential := &credentialTransport{token: "first"}
+	client := &http.Client{Transport: NewConditionalTransport(credential)}
+	for _, token := range []string{"first", "second"} {
+		credential.token = token
+		resp, err := client.Get(server.URL + "/repos/o/r/deployments")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "deployments" {
+			t.Errorf("response = %d %q, want 200 \"deployments\"", resp.StatusCode, body)
+		}
+	}
+
+	stats := client.Transport.(*ConditionalTransport).Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 1 hit and 1 miss", stats)
+	}
+	if len(validatedWith) != 1 || validatedWith[0] != "Bearer second" {
+		t.Errorf("validated with %v, want the ETag validated with the second credential", validatedWith)
+	}
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+)
+
+// NewHTTPTransport returns the transport requests to GitHub, and the other forges and Argo CD, are sent with,
+// trusting the CA bundle, using the proxy and applying the timeouts of conf. Without a proxy in conf the
+// proxy of the HTTPS_PROXY and NO_PROXY environment variables is used.
+//
+// The timeouts apply to each attempt of a request. There is no timeout for the whole request,
+// the RateLimitTransport may wait for a rate limit reset in between attempts.
+func NewHTTPTransport(conf *config.Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if conf.CABundlePath != "" {
+		bundle, err := os.ReadFile(conf.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", conf.CABundlePath)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	if conf.ProxyURL != "" {
+		proxy, err := url.Parse(conf.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s: %w", conf.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if conf.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: conf.ConnectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = conf.ConnectTimeout
+	}
+	if conf.RequestTimeout > 0 {
+		transport.ResponseHeaderTimeout = conf.RequestTimeout
+	}
+	return transport, nil
+}
+
+// enterpriseURLs returns the API and upload URL of the GitHub Enterprise Server at baseURL,
+// e.g. https://github.example.com/api/v3/ and https://github.example.com/api/uploads/.
+// uploadURL defaults to the server of baseURL.
+func enterpriseURLs(baseURL, uploadURL string) (string, string, error) {
+	api, err := url.Parse(baseURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid github base URL %s: %w", baseURL, err)
+	}
+	if !strings.HasSuffix(api.Path, "/") {
+		api.Path += "/"
+	}
+	// same rules as github.Client.WithEnterpriseURLs, hosts like api.github.example.com serve the API at /
+	if !strings.HasSuffix(api.Path, "/api/v3/") && !strings.HasPrefix(api.Host, "api.") && !strings.Contains(api.Host, ".api.") {
+		api.Path += "api/v3/"
+	}
+
+	if uploadURL == "" {
+		upload := *api
+		upload.Path = strings.TrimSuffix(upload.Path, "api/v3/")
+		uploadURL = upload.String()
+	}
+	return api.String(), uploadURL, nil
+}
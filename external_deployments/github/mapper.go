@@ -39,6 +39,15 @@ func toDeployments(ghDeployments []*github.Deployment) []*model.Deployment {
 	return deployments
 }
 
+// copyDeployment returns a copy of d without commits
+func copyDeployment(d *model.Deployment) *model.Deployment {
+	c := *d
+	c.ComparisonURL = ""
+	c.Added = []*model.Commit{}
+	c.Removed = []*model.Commit{}
+	return &c
+}
+
 func toCommit(commit *github.RepositoryCommit) *model.Commit {
 	return &model.Commit{
 		SHA:   commit.GetSHA(), // sha somehow stored in commit, not commit.Commit
@@ -22,22 +22,25 @@ func NewMockAPI() API {
 	}
 }
 
-func (gc *MockGithubClient) GetRepository(_ context.Context, repoName string) (*github.Repository, *github.Response, error) {
+func (gc *MockGithubClient) GetRepository(_ context.Context, owner, repoName string) (*github.Repository, *github.Response, error) {
 	time.Sleep(500 * time.Millisecond)
 
+	if owner == "" {
+		owner = gc.owner
+	}
 	repo := &github.Repository{
 		ID:          github.Ptr(int64(123456)),
 		Name:        github.Ptr(repoName),
-		FullName:    github.Ptr(gc.owner + "/" + repoName),
+		FullName:    github.Ptr(owner + "/" + repoName),
 		Description: github.Ptr("This is a mocked repository for testing"),
-		HTMLURL:     github.Ptr("https://github.com/" + gc.owner + "/" + repoName),
+		HTMLURL:     github.Ptr("https://github.com/" + owner + "/" + repoName),
 		Private:     github.Ptr(true),
 	}
 
 	return repo, &github.Response{NextPage: 0}, nil
 }
 
-func (gc *MockGithubClient) ListDeployments(_ context.Context, _ string, _ *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error) {
+func (gc *MockGithubClient) ListDeployments(_ context.Context, _, _ string, _ *github.DeploymentsListOptions) ([]*github.Deployment, *github.Response, error) {
 	time.Sleep(400 * time.Millisecond)
 
 	length := 100
@@ -66,7 +69,7 @@ func (gc *MockGithubClient) ListDeployments(_ context.Context, _ string, _ *gith
 	return deploys, &github.Response{NextPage: 0, Rate: github.Rate{Remaining: 1000}}, nil
 }
 
-func (gc *MockGithubClient) ListDeploymentStatuses(_ context.Context, _ string, _ int64, _ *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error) {
+func (gc *MockGithubClient) ListDeploymentStatuses(_ context.Context, _, _ string, _ int64, _ *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error) {
 	time.Sleep(300 * time.Millisecond)
 
 	states := []string{"waiting", "queued", "in_progress", "success", "inactive"}
@@ -94,7 +97,7 @@ func (gc *MockGithubClient) ListDeploymentStatuses(_ context.Context, _ string,
 	return statuses, &github.Response{NextPage: 0, Rate: github.Rate{Remaining: 1000}}, nil
 }
 
-func (gc *MockGithubClient) CompareCommits(_ context.Context, _, _, _ string, _ *github.ListOptions) (*github.CommitsComparison, error) {
+func (gc *MockGithubClient) CompareCommits(_ context.Context, _, _, _, _ string, _ *github.ListOptions) (*github.CommitsComparison, error) {
 	time.Sleep(500 * time.Millisecond)
 
 	commitCmp := &github.CommitsComparison{
@@ -0,0 +1,155 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+// Credential is a credential of a TokenPoolTransport governed by its rate limit,
+// it is implemented by RateLimitTransport and InstallationsTransport
+type Credential interface {
+	http.RoundTripper
+	// budget returns the calls the credential can make for req before waiting, math.MaxInt if unknown,
+	// and the time the budget is refilled
+	budget(req *http.Request, now time.Time) (int, time.Time)
+}
+
+// TokenPoolTransport spreads requests over several credentials, each governed by its own rate limit.
+// A request is sent with the credential with the most remaining budget, credentials without a known
+// budget count as full and ties are taken round-robin. If the chosen credential is rate limited the
+// request falls back to the others, once all are drained the error of the credential resetting first
+// is returned.
+type TokenPoolTransport struct {
+	limiters []Credential
+	next     atomic.Uint64
+}
+
+func NewTokenPoolTransport(limiters []Credential) (*TokenPoolTransport, error) {
+	if len(limiters) == 0 {
+		return nil, fmt.Errorf("no credentials provided")
+	}
+	return &TokenPoolTransport{
+		limiters: limiters,
+	}, nil
+}
+
+func (t *TokenPoolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var rateLimitErr *model.RateLimitError
+	for i, limiter := range t.candidates(req) {
+		if i > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				break
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := limiter.RoundTrip(req)
+		var limitErr *model.RateLimitError
+		if !errors.As(err, &limitErr) {
+			return resp, err
+		}
+		if rateLimitErr == nil || limitErr.Reset.Before(rateLimitErr.Reset) {
+			rateLimitErr = limitErr
+		}
+		log.Debugf("github credential %d rate limited, trying the next one", i)
+	}
+	return nil, rateLimitErr
+}
+
+// candidates returns the credentials by remaining budget for req, most first. Drained credentials follow
+// by their reset, earliest first.
+func (t *TokenPoolTransport) candidates(req *http.Request) []Credential {
+	type candidate struct {
+		limiter   Credential
+		remaining int
+		reset     time.Time
+	}
+	now := time.Now()
+	start := int(t.next.Add(1) % uint64(len(t.limiters)))
+
+	candidates := make([]candidate, 0, len(t.limiters))
+	for i := range t.limiters {
+		// rotate the start for round-robin among equal budgets
+		limiter := t.limiters[(start+i)%len(t.limiters)]
+		remaining, reset := limiter.budget(req, now)
+		candidates = append(candidates, candidate{limiter: limiter, remaining: remaining, reset: reset})
+	}
+
+	sorted := make([]Credential, 0, len(candidates))
+	for len(candidates) > 0 {
+		best := 0
+		for i, c := range candidates[1:] {
+			b := candidates[best]
+			if c.remaining > b.remaining || (c.remaining == 0 && b.remaining == 0 && c.reset.Before(b.reset)) {
+				best = i + 1
+			}
+		}
+		sorted = append(sorted, candidates[best].limiter)
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return sorted
+}
+
+// budget returns the calls the transport can make before waiting, math.MaxInt if unknown, and the time
+// the budget is refilled. A transport blocked by a secondary rate limit or below the reserve has no budget.
+func (t *RateLimitTransport) budget(_ *http.Request, now time.Time) (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.blockedUntil.After(now):
+		return 0, t.blockedUntil
+	case t.remaining < 0 || !t.reset.After(now):
+		return math.MaxInt, time.Time{}
+	case t.remaining <= rateLimitReserve:
+		return 0, t.reset
+	default:
+		return t.remaining - rateLimitReserve, t.reset
+	}
+}
+
+// tokenTransport authenticates requests to the API with a personal access token, read for each request
+type tokenTransport struct {
+	base   http.RoundTripper
+	apiURL *url.URL
+	token  secret.Secret
+}
+
+func newTokenTransport(base http.RoundTripper, apiURL string, token secret.Secret) (*tokenTransport, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid github API URL %s: %w", apiURL, err)
+	}
+	return &tokenTransport{
+		base:   base,
+		apiURL: u,
+		token:  token,
+	}, nil
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.apiURL.Host {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.token.Value()
+	if err != nil {
+		return nil, err
+	}
+	// a RoundTripper must not modify the request
+	authReq := req.Clone(req.Context())
+	authReq.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(authReq)
+}
@@ -0,0 +1,213 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+const (
+	// rateLimitReserve is the number of calls kept in reserve, requests wait or fail below it
+	rateLimitReserve  = 10
+	defaultMaxRetries = 3
+	defaultMaxWait    = 15 * time.Minute
+	retryBaseDelay    = 500 * time.Millisecond
+)
+
+// RateLimitTransport governs the requests of all goroutines sharing it by the rate limit GitHub reports.
+//
+// Once the remaining budget falls below a small reserve, requests wait until the reset if waiting
+// is enabled, otherwise they fail with a model.RateLimitError. Secondary rate limits are honoured by their
+// Retry-After header, transient 5xx responses are retried with exponential backoff.
+type RateLimitTransport struct {
+	base       http.RoundTripper
+	wait       bool
+	maxWait    time.Duration
+	maxRetries int
+	// retryDelay is the backoff before the first retry of a 5xx response, it doubles with each retry
+	retryDelay time.Duration
+
+	mu        sync.Mutex
+	remaining int // -1 until the first response
+	reset     time.Time
+	// blockedUntil is set by a secondary rate limit
+	blockedUntil time.Time
+}
+
+func NewRateLimitTransport(base http.RoundTripper, conf *config.Config) *RateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	maxWait := conf.RateLimitMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultMaxWait
+	}
+	maxRetries := conf.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	} else if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	return &RateLimitTransport{
+		base:       base,
+		wait:       conf.RateLimitWait,
+		maxWait:    maxWait,
+		maxRetries: maxRetries,
+		retryDelay: retryBaseDelay,
+		remaining:  -1,
+	}
+}
+
+// Rate returns the last known remaining budget and its reset time, remaining is -1 if unknown
+func (t *RateLimitTransport) Rate() (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.remaining, t.reset
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.awaitBudget(ctx); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry request with body to %s", req.URL.Path)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.update(resp)
+
+		retry, delay := t.classify(resp, attempt)
+		if !retry {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+
+		if delay > 0 {
+			log.Debugf("retrying %s %s in %v (attempt %d)", req.Method, req.URL.Path, delay, attempt+1)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// awaitBudget blocks until requests are allowed again or returns a model.RateLimitError
+func (t *RateLimitTransport) awaitBudget(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	var until time.Time
+	secondary := false
+	switch {
+	case t.blockedUntil.After(now):
+		until, secondary = t.blockedUntil, true
+	case t.remaining >= 0 && t.remaining <= rateLimitReserve && t.reset.After(now):
+		until = t.reset
+	}
+	t.mu.Unlock()
+
+	if until.IsZero() {
+		return nil
+	}
+	if !t.wait || time.Until(until) > t.maxWait {
+		return &model.RateLimitError{Reset: until, Secondary: secondary}
+	}
+
+	log.Infof("github rate limit nearly exhausted, waiting until %v", until.Format(time.RFC3339))
+	if err := sleep(ctx, time.Until(until)); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// the budget is refilled at the reset, the next response reports the exact value
+	if !t.reset.After(time.Now()) {
+		t.remaining = -1
+	}
+	return nil
+}
+
+// update records the rate limit reported by resp
+func (t *RateLimitTransport) update(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	resetUnix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" && resource != "core" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remaining = remaining
+	t.reset = time.Unix(resetUnix, 0)
+}
+
+// classify decides if resp is retried and how long to wait before.
+// Rate limited responses are retried after awaitBudget, which waits or fails.
+func (t *RateLimitTransport) classify(resp *http.Response, attempt int) (bool, time.Duration) {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			seconds, err := strconv.Atoi(retryAfter)
+			if err != nil {
+				return false, 0
+			}
+			t.mu.Lock()
+			t.blockedUntil = time.Now().Add(time.Duration(seconds) * time.Second)
+			t.mu.Unlock()
+			return attempt < t.maxRetries, 0
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return attempt < t.maxRetries, 0
+		}
+		return false, 0
+
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if attempt >= t.maxRetries {
+			return false, 0
+		}
+		delay := t.retryDelay << attempt
+		return true, delay + rand.N(delay/2)
+
+	default:
+		return false, 0
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,322 @@
+package github
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// response is a response of a scriptedServer
+type response struct {
+	status  int
+	headers map[string]string
+}
+
+// scriptedServer answers the nth request with the nth response, and with the last one once they are used up.
+// It records the bodies of the requests.
+type scriptedServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []response
+	bodies    []string
+}
+
+func newScriptedServer(t *testing.T, responses ...response) *scriptedServer {
+	t.Helper()
+	s := &scriptedServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		resp := s.responses[min(len(s.bodies), len(s.responses)-1)]
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		for k, v := range resp.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *scriptedServer) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+// rate returns rate limit headers with remaining calls, reset after resetIn
+func rate(remaining int, resetIn time.Duration) map[string]string {
+	return map[string]string{
+		"X-RateLimit-Remaining": strconv.Itoa(remaining),
+		"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(resetIn).Unix(), 10),
+	}
+}
+
+func newTestRateLimitTransport(conf *config.Config) *RateLimitTransport {
+	t := NewRateLimitTransport(nil, conf)
+	t.retryDelay = time.Millisecond
+	return t
+}
+
+func send(t *testing.T, transport http.RoundTripper, method, url, body string) (*http.Response, error) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestRateLimitTransportFailsBelowReserve(t *testing.T) {
+	server := newScriptedServer(t, response{status: http.StatusOK, headers: rate(rateLimitReserve, time.Hour)})
+	transport := newTestRateLimitTransport(&config.Config{})
+
+	if _, err := send(t, transport, http.MethodGet, server.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _ := transport.Rate(); remaining != rateLimitReserve {
+		t.Errorf("Rate() remaining = %d, want %d", remaining, rateLimitReserve)
+	}
+
+	_, err := send(t, transport, http.MethodGet, server.URL, "")
+	var rateLimitErr *model.RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.Secondary {
+		t.Fatalf("error = %v, want primary *model.RateLimitError", err)
+	}
+	if !errors.Is(err, model.ErrRateLimited) {
+		t.Errorf("error = %v, want it to match model.ErrRateLimited", err)
+	}
+	if got := server.calls(); got != 1 {
+		t.Errorf("calls = %d, want the request below the reserve not sent", got)
+	}
+}
+
+func TestRateLimitTransportWaitsForReset(t *testing.T) {
+	server := newScriptedServer(t,
+		response{status: http.StatusOK, headers: rate(1, time.Second)},
+		response{status: http.StatusOK, headers: rate(4999, time.Hour)},
+	)
+	transport := newTestRateLimitTransport(&config.Config{RateLimitWait: true, RateLimitMaxWait: 5 * time.Second})
+
+	if _, err := send(t, transport, http.MethodGet, server.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	_, reset := transport.Rate()
+	if _, err := send(t, transport, http.MethodGet, server.URL, ""); err != nil {
+		t.Fatalf("error = %v, want the request sent after the reset", err)
+	}
+	if now := time.Now(); now.Before(reset) {
+		t.Errorf("request sent at %v, before the reset at %v", now, reset)
+	}
+	if remaining, _ := transport.Rate(); remaining != 4999 {
+		t.Errorf("Rate() remaining = %d, want 4999", remaining)
+	}
+}
+
+func TestRateLimitTransportFailsIfResetExceedsMaxWait(t *testing.T) {
+	server := newScriptedServer(t, response{status: http.StatusOK, headers: rate(0, time.Hour)})
+	transport := newTestRateLimitTransport(&config.Config{RateLimitWait: true, RateLimitMaxWait: time.Minute})
+
+	if _, err := send(t, transport, http.MethodGet, server.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err := send(t, transport, http.MethodGet, server.URL, "")
+	var rateLimitErr *model.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("error = %v, want *model.RateLimitError", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("failed after %v, want to fail without waiting", elapsed)
+	}
+}
+
+func TestRateLimitTransportHonoursRetryAfter(t *testing.T) {
+	secondary := response{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "1"}}
+
+	t.Run("fail", func(t *testing.T) {
+		server := newScriptedServer(t, secondary)
+		transport := newTestRateLimitTransport(&config.Config{})
+
+		_, err := send(t, transport, http.MethodGet, server.URL, "")
+		var rateLimitErr *model.RateLimitError
+		if !errors.As(err, &rateLimitErr) || !rateLimitErr.Secondary {
+			t.Fatalf("error = %v, want secondary *model.RateLimitError", err)
+		}
+		if until := time.Until(rateLimitErr.Reset); until <= 0 || until > time.Second {
+			t.Errorf("blocked for %v, want the Retry-After of 1s", until)
+		}
+		// the block applies to the following requests as well
+		if _, err := send(t, transport, http.MethodGet, server.URL, ""); !errors.As(err, &rateLimitErr) {
+			t.Errorf("error = %v, want *model.RateLimitError", err)
+		}
+		if got := server.calls(); got != 1 {
+			t.Errorf("calls = %d, want 1", got)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		server := newScriptedServer(t, secondary, response{status: http.StatusOK})
+		transport := newTestRateLimitTransport(&config.Config{RateLimitWait: true})
+
+		start := time.Now()
+		resp, err := send(t, transport, http.MethodGet, server.URL, "")
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("response = %v, error = %v, want 200 after the Retry-After", resp, err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %v, want after the Retry-After of 1s", elapsed)
+		}
+		if got := server.calls(); got != 2 {
+			t.Errorf("calls = %d, want 2", got)
+		}
+	})
+}
+
+func TestRateLimitTransportRetriesServerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		responses  []response
+		wantStatus int
+		wantCalls  int
+	}{
+		{
+			name:       "recovers",
+			responses:  []response{{status: http.StatusBadGateway}, {status: http.StatusServiceUnavailable}, {status: http.StatusOK}},
+			wantStatus: http.StatusOK,
+			wantCalls:  3,
+		},
+		{
+			name:       "max retries",
+			maxRetries: 2,
+			responses:  []response{{status: http.StatusInternalServerError}},
+			wantStatus: http.StatusInternalServerError,
+			wantCalls:  3,
+		},
+		{
+			name:       "retries disabled",
+			maxRetries: -1,
+			responses:  []response{{status: http.StatusGatewayTimeout}},
+			wantStatus: http.StatusGatewayTimeout,
+			wantCalls:  1,
+		},
+		{
+			name:       "client error",
+			responses:  []response{{status: http.StatusNotFound}},
+			wantStatus: http.StatusNotFound,
+			wantCalls:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedServer(t, tt.responses...)
+			transport := newTestRateLimitTransport(&config.Config{MaxRetries: tt.maxRetries})
+
+			resp, err := send(t, transport, http.MethodGet, server.URL, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := server.calls(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransportBacksOffWithJitter(t *testing.T) {
+	transport := NewRateLimitTransport(nil, &config.Config{MaxRetries: 5})
+	resp := &http.Response{StatusCode: http.StatusBadGateway}
+
+	jittered := false
+	for attempt := range 5 {
+		base := retryBaseDelay << attempt
+		for range 20 {
+			retry, delay := transport.classify(resp, attempt)
+			if !retry {
+				t.Fatalf("attempt %d not retried", attempt)
+			}
+			if delay < base || delay >= base+base/2 {
+				t.Fatalf("delay of attempt %d = %v, want in [%v, %v)", attempt, delay, base, base+base/2)
+			}
+			jittered = jittered || delay != base
+		}
+	}
+	if !jittered {
+		t.Error("delays have no jitter")
+	}
+	if retry, _ := transport.classify(resp, 5); retry {
+		t.Error("attempt 5 retried, want at most 5 retries")
+	}
+}
+
+func TestRateLimitTransportReplaysBody(t *testing.T) {
+	server := newScriptedServer(t, response{status: http.StatusBadGateway}, response{status: http.StatusOK})
+	transport := newTestRateLimitTransport(&config.Config{})
+
+	resp, err := send(t, transport, http.MethodPost, server.URL, `{"ref":"main"}`)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("response = %v, error = %v, want 200", resp, err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for i, body := range server.bodies {
+		if body != `{"ref":"main"}` {
+			t.Errorf("body of request %d = %q, want the body replayed", i, body)
+		}
+	}
+}
+
+func TestRateLimitTransportCannotReplayBodyWithoutGetBody(t *testing.T) {
+	server := newScriptedServer(t, response{status: http.StatusBadGateway}, response{status: http.StatusOK})
+	transport := newTestRateLimitTransport(&config.Config{})
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "cannot retry") {
+		t.Errorf("error = %v, want the request not retried", err)
+	}
+	if got := server.calls(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestRateLimitTransportIgnoresOtherResources(t *testing.T) {
+	headers := rate(0, time.Hour)
+	headers["X-RateLimit-Resource"] = "search"
+	server := newScriptedServer(t, response{status: http.StatusOK, headers: headers})
+	transport := newTestRateLimitTransport(&config.Config{})
+
+	for range 2 {
+		if _, err := send(t, transport, http.MethodGet, server.URL, ""); err != nil {
+			t.Fatalf("error = %v, want the core budget unaffected by the search rate limit", err)
+		}
+	}
+	if remaining, _ := transport.Rate(); remaining != -1 {
+		t.Errorf("Rate() remaining = %d, want -1", remaining)
+	}
+}
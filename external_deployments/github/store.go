@@ -0,0 +1,49 @@
+package github
+
+import (
+	"time"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// Store persists the data cached by the DeploymentClient, so it survives restarts.
+// repo is the full name owner/name of the repository.
+type Store interface {
+	// LoadDeployments returns the stored deployments, newest first, or nil if none are stored.
+	// fetchedAt is the time the deployments were first loaded, they expire after config.Config.CacheTTL.
+	LoadDeployments(repo string) (deploys []*github.Deployment, fetchedAt time.Time, err error)
+	// SaveDeployments replaces the stored deployments. The success times of the deleted deployments are removed
+	// with them, and so are the comparisons of their commits unless deploys deploy the commit as well.
+	SaveDeployments(repo string, deploys []*github.Deployment, fetchedAt time.Time, deleted []*github.Deployment) error
+
+	// LoadSucceededAt returns the time of the success status per deployment ID
+	LoadSucceededAt(repo string) (map[int64]time.Time, error)
+	// SaveSucceededAt adds succeededAt to the stored success times
+	SaveSucceededAt(repo string, succeededAt map[int64]time.Time) error
+
+	// LoadComparison returns the stored comparison of base...head or nil if none is stored
+	LoadComparison(repo, base, head string) (*github.CommitsComparison, error)
+	SaveComparison(repo, base, head string, cmp *github.CommitsComparison) error
+
+	// Invalidate removes all stored data of repo, including the data of its environments
+	Invalidate(repo string) error
+	Close() error
+}
+
+// nopStore is used if no store is configured, the data is only cached in memory
+type nopStore struct{}
+
+func (nopStore) LoadDeployments(string) ([]*github.Deployment, time.Time, error) {
+	return nil, time.Time{}, nil
+}
+func (nopStore) SaveDeployments(string, []*github.Deployment, time.Time, []*github.Deployment) error {
+	return nil
+}
+func (nopStore) LoadSucceededAt(string) (map[int64]time.Time, error) { return nil, nil }
+func (nopStore) SaveSucceededAt(string, map[int64]time.Time) error   { return nil }
+func (nopStore) LoadComparison(string, string, string) (*github.CommitsComparison, error) {
+	return nil, nil
+}
+func (nopStore) SaveComparison(string, string, string, *github.CommitsComparison) error { return nil }
+func (nopStore) Invalidate(string) error                                                { return nil }
+func (nopStore) Close() error                                                           { return nil }
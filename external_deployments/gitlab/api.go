@@ -0,0 +1,201 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/github"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+const defaultBaseURL = "https://gitlab.com/api/v4/"
+
+// API mock for testing
+type API interface {
+	ListDeployments(ctx context.Context, project string, opts *DeploymentsListOptions) ([]*Deployment, *Response, error)
+	Compare(ctx context.Context, project, from, to string) (*Comparison, error)
+}
+
+// DeploymentsListOptions filters the deployments of a project, see
+// https://docs.gitlab.com/api/deployments/#list-project-deployments
+type DeploymentsListOptions struct {
+	Environment   string
+	Status        string
+	Page, PerPage int
+}
+
+// Response holds the pagination of a list request
+type Response struct {
+	NextPage int
+}
+
+// ErrorResponse is returned for API responses with an error status
+type ErrorResponse struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("gitlab API returned %d: %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	client      *http.Client
+	baseURL     *url.URL
+	token       secret.Secret
+	environment string
+}
+
+func NewAPI(conf *config.Config) (API, error) {
+	env := conf.Env
+	if len(env) == 0 {
+		env = "production"
+	}
+	if len(conf.Token) == 0 && len(conf.TokenFile) == 0 {
+		return nil, fmt.Errorf("no external deployments auth token provided")
+	}
+	token, err := secret.New(conf.Token, conf.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := github.NewHTTPTransport(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := conf.BaseURL
+	if len(baseURL) == 0 {
+		baseURL = defaultBaseURL
+	}
+	return NewGitlabClient(&http.Client{Transport: transport}, baseURL, token, env)
+}
+
+func NewGitlabClient(client *http.Client, baseURL string, token secret.Secret, environment string) (API, error) {
+	if client == nil {
+		return nil, fmt.Errorf("http client cannot be nil")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gitlab base URL %s: %w", baseURL, err)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &Client{
+		client:      client,
+		baseURL:     u,
+		token:       token,
+		environment: environment,
+	}, nil
+}
+
+func (gc *Client) ListDeployments(ctx context.Context, project string, opts *DeploymentsListOptions) ([]*Deployment, *Response, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("listDeployments took %v\n", time.Since(start))
+	}()
+
+	env := opts.Environment
+	if env == "" {
+		env = gc.environment
+	}
+	query := url.Values{
+		"environment": {env},
+		"order_by":    {"updated_at"},
+		"sort":        {"desc"},
+	}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+
+	var deploys []*Deployment
+	httpResp, err := gc.get(ctx, "projects/"+url.PathEscape(project)+"/deployments", query, &deploys)
+	if err != nil {
+		return nil, nil, err
+	}
+	nextPage, _ := strconv.Atoi(httpResp.Header.Get("X-Next-Page"))
+	return deploys, &Response{NextPage: nextPage}, nil
+}
+
+func (gc *Client) Compare(ctx context.Context, project, from, to string) (*Comparison, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("compare took %v\n", time.Since(start))
+	}()
+
+	query := url.Values{
+		"from": {from},
+		"to":   {to},
+	}
+	var cmp Comparison
+	if _, err := gc.get(ctx, "projects/"+url.PathEscape(project)+"/repository/compare", query, &cmp); err != nil {
+		return nil, err
+	}
+	return &cmp, nil
+}
+
+// get requests path relative to the base URL and decodes the JSON response into v
+func (gc *Client) get(ctx context.Context, path string, query url.Values, v any) (*http.Response, error) {
+	// path holds the escaped project path, keep it escaped in the URL
+	rawPath := gc.baseURL.EscapedPath() + path
+	unescaped, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	u := *gc.baseURL
+	u.Path, u.RawPath = unescaped, rawPath
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := gc.token.Value()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := gc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		reset := time.Now().Add(time.Minute)
+		if unix, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+			reset = time.Unix(unix, 0)
+		}
+		return nil, &model.RateLimitError{Reset: reset}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Message any `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, &ErrorResponse{StatusCode: resp.StatusCode, Message: fmt.Sprint(body.Message)}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("error while decoding gitlab response of %s: %w", path, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,171 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+const (
+	defaultMaxConcurrency = 8
+	deploymentsPerPage    = 100
+)
+
+// DeploymentClient lists the deployments of GitLab projects. It is safe for concurrent use.
+//
+// GitLab filters deployments by status and sorts them by update time, so only the deployments
+// updated since the queried range started are listed and nothing is cached.
+type DeploymentClient struct {
+	api            API
+	maxConcurrency int
+}
+
+func NewDeploymentClient(api API, conf *config.Config) (*DeploymentClient, error) {
+	if api == nil {
+		return nil, fmt.Errorf("api cannot be nil")
+	}
+	maxConcurrency := conf.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	return &DeploymentClient{
+		api:            api,
+		maxConcurrency: maxConcurrency,
+	}, nil
+}
+
+// ListDeploymentsInRange lists deployments of the project q.Owner/q.Repo which succeeded in range [q.From, q.To]
+func (gdc *DeploymentClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	if q.Repo == "" {
+		return nil, fmt.Errorf("no repository set in query")
+	}
+	project := q.Repo
+	if q.Owner != "" {
+		project = q.Owner + "/" + q.Repo
+	}
+
+	inRange, oneBefore, err := gdc.listSuccessfulInRange(ctx, project, q.Environment, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if oneBefore != nil {
+		inRange = append(inRange, oneBefore)
+	}
+
+	populated, err := gdc.populateWithCommits(ctx, project, inRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove one before
+	if oneBefore != nil {
+		populated = populated[:len(populated)-1]
+	}
+	return populated, nil
+}
+
+// listSuccessfulInRange lists the successful deployments of environment, the configured one if empty,
+// finished in range [from, to], newest first, and the newest one finished before from.
+//
+// The deployments are listed by update time, newest first. A deployment is updated when it finishes and
+// may be updated again later, e.g. when a newer deployment supersedes it, so the finish time is filtered
+// here: deployments updated after to may have finished in range. Listing stops once the updates are older
+// than the newest deployment finished before from, the remaining ones finished even earlier.
+func (gdc *DeploymentClient) listSuccessfulInRange(ctx context.Context, project, environment string, from, to time.Time) ([]*model.Deployment, *model.Deployment, error) {
+	var inRange []*model.Deployment
+	var oneBefore *model.Deployment
+
+	opts := &DeploymentsListOptions{
+		Environment: environment,
+		Status:      "success",
+		Page:        1,
+		PerPage:     deploymentsPerPage,
+	}
+
+	for opts.Page > 0 {
+		deploys, resp, err := gdc.api.ListDeployments(ctx, project, opts)
+		if err != nil {
+			var glErr *ErrorResponse
+			if errors.As(err, &glErr) && glErr.StatusCode == http.StatusNotFound {
+				return nil, nil, fmt.Errorf("%w: %s", model.ErrRepositoryNotFound, project)
+			}
+			return nil, nil, fmt.Errorf("error while fetching gitlab deployments: %w", err)
+		}
+
+		for _, d := range deploys {
+			deploy := toDeployment(d)
+			if deploy.SucceededAt.Before(from) {
+				if oneBefore == nil || deploy.SucceededAt.After(oneBefore.SucceededAt) {
+					oneBefore = deploy
+				}
+				continue
+			}
+			if !deploy.SucceededAt.After(to) {
+				inRange = append(inRange, deploy)
+			}
+		}
+		if oneBefore != nil && len(deploys) > 0 && deploys[len(deploys)-1].UpdatedAt.Before(oneBefore.SucceededAt) {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	slices.SortFunc(inRange, func(a, b *model.Deployment) int {
+		return b.SucceededAt.Compare(a.SucceededAt)
+	})
+	return inRange, oneBefore, nil
+}
+
+// populateWithCommits sets the commits added and removed by each deployment compared to the next older one.
+// GitLab compares from the merge base, so both directions are compared.
+func (gdc *DeploymentClient) populateWithCommits(ctx context.Context, project string, deployments []*model.Deployment) ([]*model.Deployment, error) {
+	if len(deployments) <= 1 {
+		return deployments, nil
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(gdc.maxConcurrency)
+	start := time.Now()
+
+	for i := range len(deployments) - 1 {
+		g.Go(func() error {
+			d := deployments[i]
+			head := deployments[i].SHA
+			base := deployments[i+1].SHA
+			if head == base {
+				return nil
+			}
+
+			addedCmp, err := gdc.api.Compare(gCtx, project, base, head)
+			if err != nil {
+				return fmt.Errorf("error while comparing commits: %w", err)
+			}
+			d.ComparisonURL = addedCmp.WebURL
+			d.Added = toCommits(addedCmp)
+
+			removedCmp, err := gdc.api.Compare(gCtx, project, head, base)
+			if err != nil {
+				return fmt.Errorf("error comparing removed commits: %w", err)
+			}
+			d.Removed = toCommits(removedCmp)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	log.Tracef("comparing %d times took %v", len(deployments)-1, time.Since(start))
+	return deployments, nil
+}
@@ -0,0 +1,103 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+var baseTime = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+// fakeAPI lists deployments by update time, newest first, perPage per page
+type fakeAPI struct {
+	deployments []*Deployment
+	perPage     int
+	pages       int
+}
+
+// at returns the time minutes after baseTime
+func at(minutes int) time.Time {
+	return baseTime.Add(time.Duration(minutes) * time.Minute)
+}
+
+func (f *fakeAPI) add(id int64, finished, updated int) {
+	finishedAt := at(finished)
+	f.deployments = append(f.deployments, &Deployment{
+		ID:         id,
+		SHA:        fmt.Sprintf("sha%d", id),
+		Status:     "success",
+		CreatedAt:  finishedAt.Add(-time.Minute),
+		UpdatedAt:  at(updated),
+		Deployable: &Deployable{Status: "success", FinishedAt: &finishedAt},
+	})
+	slices.SortStableFunc(f.deployments, func(a, b *Deployment) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+}
+
+func (f *fakeAPI) ListDeployments(_ context.Context, _ string, opts *DeploymentsListOptions) ([]*Deployment, *Response, error) {
+	f.pages++
+	start := min((opts.Page-1)*f.perPage, len(f.deployments))
+	end := min(start+f.perPage, len(f.deployments))
+	resp := &Response{}
+	if end < len(f.deployments) {
+		resp.NextPage = opts.Page + 1
+	}
+	return f.deployments[start:end], resp, nil
+}
+
+func (f *fakeAPI) Compare(_ context.Context, _, from, to string) (*Comparison, error) {
+	return &Comparison{WebURL: "https://gitlab.com/o/r/-/compare/" + from + "..." + to}, nil
+}
+
+func ids(deployments []*model.Deployment) []int64 {
+	result := make([]int64, len(deployments))
+	for i, d := range deployments {
+		result[i] = d.ID
+	}
+	return result
+}
+
+func TestListDeploymentsInRangeFiltersByFinishTime(t *testing.T) {
+	api := &fakeAPI{perPage: 2}
+	// finished in range, updated after it when the next deployment superseded it
+	api.add(3, 20, 90)
+	api.add(4, 25, 26)
+	api.add(5, 70, 71)
+	// finished before the range, 1 finished later than 2 but was updated earlier
+	api.add(2, 5, 8)
+	api.add(1, 7, 7)
+	// listed with 1 on the last page, the older ones are not listed
+	api.add(0, 1, 2)
+	api.add(6, 0, 1)
+
+	client, err := NewDeploymentClient(api, &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployments, err := client.ListDeploymentsInRange(context.Background(), models.DeploymentsQuery{
+		Owner: "o",
+		Repo:  "r",
+		From:  at(10),
+		To:    at(60),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := ids(deployments), []int64{4, 3}; !slices.Equal(got, want) {
+		t.Errorf("deployments = %v, want %v", got, want)
+	}
+	if got := deployments[1].ComparisonURL; got != "https://gitlab.com/o/r/-/compare/sha1...sha3" {
+		t.Errorf("comparison of the oldest deployment = %s, want it compared to the newest one finished before the range", got)
+	}
+	if api.pages != 3 {
+		t.Errorf("listed %d pages, want 3", api.pages)
+	}
+}
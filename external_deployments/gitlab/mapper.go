@@ -0,0 +1,38 @@
+package gitlab
+
+import (
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// toDeployment maps a successful deployment, its SucceededAt is the time the deployment job finished
+func toDeployment(d *Deployment) *model.Deployment {
+	if d == nil {
+		return nil
+	}
+	return &model.Deployment{
+		ID:            d.ID,
+		SHA:           d.SHA,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+		SucceededAt:   d.FinishedAt(),
+		ComparisonURL: "",
+		Added:         []*model.Commit{},
+		Removed:       []*model.Commit{},
+	}
+}
+
+func toCommits(cmp *Comparison) []*model.Commit {
+	commits := make([]*model.Commit, len(cmp.Commits))
+	for i, commit := range cmp.Commits {
+		commits[i] = toCommit(commit)
+	}
+	return commits
+}
+
+func toCommit(commit *Commit) *model.Commit {
+	return &model.Commit{
+		SHA:   commit.ID,
+		Title: commit.Title,
+		URL:   commit.WebURL,
+	}
+}
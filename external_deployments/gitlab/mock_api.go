@@ -0,0 +1,76 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type MockGitlabClient struct {
+	environment string
+}
+
+func NewMockAPI() API {
+	return &MockGitlabClient{
+		environment: "mock-environment",
+	}
+}
+
+func (gc *MockGitlabClient) ListDeployments(_ context.Context, _ string, _ *DeploymentsListOptions) ([]*Deployment, *Response, error) {
+	time.Sleep(400 * time.Millisecond)
+
+	length := 100
+	if val, err := strconv.Atoi(os.Getenv("MOCK_DEPLOYMENTS_LENGTH")); err == nil {
+		length = val
+	}
+	deploys := make([]*Deployment, 0, length)
+
+	// spread out finish timestamps randomly throughout the last 10 minutes
+	maxMs := (10 * time.Minute).Milliseconds()
+	for i := range length {
+		finishedAt := time.Now().Add(-time.Duration(rand.Int64N(maxMs)) * time.Millisecond)
+		deploys = append(deploys, &Deployment{
+			ID:         int64(1001 + i),
+			IID:        int64(1 + i),
+			Ref:        "main",
+			SHA:        fmt.Sprintf("def456ghi%03d", i),
+			Status:     "success",
+			CreatedAt:  finishedAt.Add(-time.Minute),
+			UpdatedAt:  finishedAt,
+			Deployable: &Deployable{ID: int64(5001 + i), Status: "success", FinishedAt: &finishedAt},
+		})
+	}
+	slices.SortFunc(deploys, func(a, b *Deployment) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+
+	return deploys, &Response{NextPage: 0}, nil
+}
+
+func (gc *MockGitlabClient) Compare(_ context.Context, project, from, to string) (*Comparison, error) {
+	time.Sleep(300 * time.Millisecond)
+
+	return &Comparison{
+		WebURL: fmt.Sprintf("https://gitlab.com/%s/-/compare/%s...%s", project, from, to),
+		Commits: []*Commit{
+			{
+				ID:      "def456ghi789",
+				ShortID: "def456gh",
+				Title:   "feat: mocked commit 1",
+				Message: "feat: mocked commit 1",
+				WebURL:  "https://gitlab.com/" + project + "/-/commit/def456ghi789",
+			},
+			{
+				ID:      "ghi789jkl012",
+				ShortID: "ghi789jk",
+				Title:   "fix: mocked commit 2",
+				Message: "fix: mocked commit 2",
+				WebURL:  "https://gitlab.com/" + project + "/-/commit/ghi789jkl012",
+			},
+		},
+	}, nil
+}
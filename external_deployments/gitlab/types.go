@@ -0,0 +1,46 @@
+package gitlab
+
+import "time"
+
+// Deployment of the GitLab deployments API, see https://docs.gitlab.com/api/deployments/
+type Deployment struct {
+	ID         int64       `json:"id"`
+	IID        int64       `json:"iid"`
+	Ref        string      `json:"ref"`
+	SHA        string      `json:"sha"`
+	Status     string      `json:"status"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Deployable *Deployable `json:"deployable,omitempty"`
+}
+
+// Deployable is the job which ran the deployment
+type Deployable struct {
+	ID         int64      `json:"id"`
+	Status     string     `json:"status"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// FinishedAt returns the time the deployment job finished, or the last update if unknown
+func (d *Deployment) FinishedAt() time.Time {
+	if d.Deployable != nil && d.Deployable.FinishedAt != nil {
+		return *d.Deployable.FinishedAt
+	}
+	return d.UpdatedAt
+}
+
+// Comparison of the repository compare API, see https://docs.gitlab.com/api/repositories/#compare-branches-tags-or-commits
+type Comparison struct {
+	Commits        []*Commit `json:"commits"`
+	CompareTimeout bool      `json:"compare_timeout"`
+	CompareSameRef bool      `json:"compare_same_ref"`
+	WebURL         string    `json:"web_url"`
+}
+
+type Commit struct {
+	ID      string `json:"id"`
+	ShortID string `json:"short_id"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	WebURL  string `json:"web_url"`
+}
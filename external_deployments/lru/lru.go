@@ -0,0 +1,148 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a size bounded cache safe for concurrent use.
+//
+// The least recently used entry is evicted once more than size entries are stored,
+// or the entries cost more than maxCost, entries older than ttl are treated as missing.
+type Cache[K comparable, V any] struct {
+	size int
+	ttl  time.Duration
+	// cost returns the cost of a value, e.g. its size in bytes, nil if the cache is bounded by size only
+	cost    func(V) int64
+	maxCost int64
+
+	mu        sync.Mutex
+	lru       *list.List // front is most recently used
+	entries   map[K]*list.Element
+	totalCost int64
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	cost      int64
+	createdAt time.Time
+}
+
+func New[K comparable, V any](size int, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// NewWithCost creates a cache which also evicts the least recently used entries once the cost of all entries
+// exceeds maxCost, an entry costing more than maxCost is not stored
+func NewWithCost[K comparable, V any](size int, ttl time.Duration, maxCost int64, cost func(V) int64) *Cache[K, V] {
+	c := New[K, V](size, ttl)
+	c.maxCost = maxCost
+	c.cost = cost
+	return c
+}
+
+// Get returns the value of key if present and not expired
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+// GetOrAdd returns the value of key, adding the value returned by create if the key is missing or expired
+func (c *Cache[K, V]) GetOrAdd(key K, create func() V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value, ok := c.get(key); ok {
+		return value
+	}
+	value := create()
+	c.add(key, value)
+	return value
+}
+
+// Add stores value for key, replacing an existing value
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(key, value)
+}
+
+// Remove deletes key from the cache
+func (c *Cache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// RemoveFunc deletes the keys for which remove returns true
+func (c *Cache[K, V]) RemoveFunc(remove func(key K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if remove(key) {
+			c.remove(elem)
+		}
+	}
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := elem.Value.(*entry[K, V])
+	if c.ttl > 0 && time.Since(e.createdAt) > c.ttl {
+		c.remove(elem)
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return e.value, true
+}
+
+func (c *Cache[K, V]) add(key K, value V) {
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	var cost int64
+	if c.cost != nil {
+		cost = c.cost(value)
+		if cost > c.maxCost {
+			return
+		}
+	}
+	c.entries[key] = c.lru.PushFront(&entry[K, V]{
+		key:       key,
+		value:     value,
+		cost:      cost,
+		createdAt: time.Now(),
+	})
+	c.totalCost += cost
+	for (c.size > 0 && c.lru.Len() > c.size) || (c.cost != nil && c.totalCost > c.maxCost) {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*entry[K, V])
+	delete(c.entries, e.key)
+	c.totalCost -= e.cost
+}
@@ -0,0 +1,49 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCostEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewWithCost[string, []byte](0, 0, 10, func(v []byte) int64 { return int64(len(v)) })
+	c.Add("a", make([]byte, 4))
+	c.Add("b", make([]byte, 4))
+	c.Get("a")
+	c.Add("c", make([]byte, 4))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b is cached, want least recently used entry evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s is not cached", key)
+		}
+	}
+
+	// replacing an entry releases its cost
+	c.Add("a", make([]byte, 6))
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c is not cached after replacing a")
+	}
+
+	c.Add("d", make([]byte, 11))
+	if _, ok := c.Get("d"); ok {
+		t.Error("d is cached, want entries costing more than maxCost not stored")
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+func TestTTLExpiresEntries(t *testing.T) {
+	c := New[string, int](0, 10*time.Millisecond)
+	c.Add("a", 1)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a is not cached")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a is cached after the TTL")
+	}
+}
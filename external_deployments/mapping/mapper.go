@@ -0,0 +1,181 @@
+package mapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/workload"
+)
+
+// Rule maps workloads to repositories
+type Rule interface {
+	// Map returns the repository, or owner/repository, of the workload, ok is false if the rule does not apply
+	Map(ctx context.Context, t *Target) (repo string, ok bool, err error)
+	// String describes the rule in traces
+	String() string
+}
+
+// Target is the workload being mapped
+type Target struct {
+	Namespace, Name string
+
+	object func() (*workload.Workload, error)
+}
+
+// Object returns the Deployment or StatefulSet of the workload, it is read from the cluster at most once per mapping
+func (t *Target) Object() (*workload.Workload, error) {
+	return t.object()
+}
+
+// Result is the repository a workload is mapped to
+type Result struct {
+	// Owner is empty if the rule did not name an owner
+	Owner, Repo string
+	// Environment is the environment annotated on the workload, empty if it has none
+	Environment string
+	// Rule is the rule which matched
+	Rule string
+	// Trace lists the outcome of each evaluated rule in order
+	Trace []string
+}
+
+// Mapper maps workloads to repositories with the first matching rule
+type Mapper struct {
+	api   workload.API
+	rules []Rule
+	// environmentAnnotation is the annotation of the workload's environment, empty to not read it
+	environmentAnnotation string
+}
+
+// NewMapper creates a mapper evaluating the annotation workload.RepoAnnotation if conf.WorkloadAnnotations is set,
+// the workloads listed by conf.Repositories, the rules of conf.WorkloadMapping and finally the default rule
+// dropping a version suffix like -v2 from the workload name.
+// api reads the workloads from the cluster, it is only required by label and annotation rules.
+func NewMapper(conf *config.Config, api workload.API) (*Mapper, error) {
+	var rules []Rule
+	if conf.WorkloadAnnotations {
+		rules = append(rules, &metadataRule{key: workload.RepoAnnotation, annotation: true})
+	}
+
+	for i := range conf.Repositories {
+		repo := &conf.Repositories[i]
+		for _, w := range repo.Workloads {
+			rules = append(rules, &explicitRule{workload: w, repo: conf.OwnerOf(repo) + "/" + repo.Name})
+		}
+	}
+
+	for i, rule := range conf.WorkloadMapping {
+		switch {
+		case rule.Workload != "":
+			rules = append(rules, &explicitRule{workload: rule.Workload, repo: rule.Repository})
+		case rule.Match != "":
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression of mapping rule %d: %w", i, err)
+			}
+			rules = append(rules, &regexRule{re: re, template: rule.Repository})
+		case rule.Label != "":
+			rules = append(rules, &metadataRule{key: rule.Label})
+		case rule.Annotation != "":
+			rules = append(rules, &metadataRule{key: rule.Annotation, annotation: true})
+		default:
+			return nil, fmt.Errorf("mapping rule %d sets none of workload, match, label and annotation", i)
+		}
+	}
+
+	for _, rule := range rules {
+		if _, ok := rule.(*metadataRule); ok && api == nil {
+			return nil, fmt.Errorf("mapping rule %s reads the workload but no workload api is configured", rule)
+		}
+	}
+
+	m := NewMapperWithRules(api, append(rules, defaultRule{}))
+	if conf.WorkloadAnnotations {
+		m.environmentAnnotation = workload.EnvironmentAnnotation
+	}
+	return m, nil
+}
+
+// NewMapperWithRules creates a mapper evaluating rules in order, api reads the workloads of the rules reading them
+func NewMapperWithRules(api workload.API, rules []Rule) *Mapper {
+	return &Mapper{
+		api:   api,
+		rules: rules,
+	}
+}
+
+// Map returns the repository of the workload namespace/name by the first matching rule.
+// Rules mapping to neither name nor owner/name, e.g. to a/b/c, do not match.
+// If no rule matches the error matches model.ErrRepositoryNotFound.
+func (m *Mapper) Map(ctx context.Context, namespace, name string) (*Result, error) {
+	t := &Target{
+		Namespace: namespace,
+		Name:      name,
+		object: sync.OnceValues(func() (*workload.Workload, error) {
+			if m.api == nil {
+				return nil, fmt.Errorf("no workload api configured")
+			}
+			return m.api.GetWorkload(ctx, namespace, name)
+		}),
+	}
+
+	var trace []string
+	for _, rule := range m.rules {
+		repo, ok, err := rule.Map(ctx, t)
+		if err == nil && ok {
+			err = checkRepo(repo)
+		}
+		if errors.Is(err, errInvalidRepository) {
+			trace = append(trace, fmt.Sprintf("%s: no match, %v", rule, err))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error while mapping workload %s/%s by %s: %w", namespace, name, rule, err)
+		}
+		if !ok {
+			trace = append(trace, fmt.Sprintf("%s: no match", rule))
+			continue
+		}
+		trace = append(trace, fmt.Sprintf("%s: matched %s", rule, repo))
+
+		owner, repoName, found := strings.Cut(repo, "/")
+		if !found {
+			owner, repoName = "", repo
+		}
+		result := &Result{
+			Owner: owner,
+			Repo:  repoName,
+			Rule:  rule.String(),
+		}
+		if m.environmentAnnotation != "" {
+			if err := m.annotatedEnvironment(t, result); err != nil {
+				return nil, err
+			}
+			if result.Environment != "" {
+				trace = append(trace, fmt.Sprintf("annotation %s: environment %s", m.environmentAnnotation, result.Environment))
+			}
+		}
+		result.Trace = trace
+		return result, nil
+	}
+	return nil, fmt.Errorf("%w: no mapping rule matched workload %s/%s: %s", model.ErrRepositoryNotFound, namespace, name, strings.Join(trace, "; "))
+}
+
+// annotatedEnvironment sets the environment of result to the one annotated on the workload, if it exists
+func (m *Mapper) annotatedEnvironment(t *Target, result *Result) error {
+	w, err := t.Object()
+	if errors.Is(err, model.ErrWorkloadNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error while reading environment of workload %s/%s: %w", t.Namespace, t.Name, err)
+	}
+	result.Environment = w.Annotations[m.environmentAnnotation]
+	return nil
+}
@@ -0,0 +1,143 @@
+package mapping
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/workload"
+)
+
+func newTestAPI(t *testing.T, objects ...runtime.Object) workload.API {
+	t.Helper()
+	api, err := workload.NewWorkloadClient(fake.NewClientset(objects...))
+	if err != nil {
+		t.Fatalf("NewWorkloadClient() error = %v", err)
+	}
+	return api
+}
+
+func annotatedDeployment(name string, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name, Annotations: annotations},
+	}
+}
+
+func TestMapRejectsInvalidRepositories(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantOwner string
+		wantRepo  string
+		wantRule  string
+	}{
+		{value: "shop", wantRepo: "shop", wantRule: "annotation github.com/repo"},
+		{value: "acme/shop", wantOwner: "acme", wantRepo: "shop", wantRule: "annotation github.com/repo"},
+		{value: "https://github.com/acme/shop.git", wantOwner: "acme", wantRepo: "shop", wantRule: "annotation github.com/repo"},
+		{value: "git@github.com:acme/shop.git", wantOwner: "acme", wantRepo: "shop", wantRule: "annotation github.com/repo"},
+		{value: "a/b/c", wantRepo: "shop", wantRule: "default"},
+		{value: "https://gitlab.com/a/b/c", wantRepo: "shop", wantRule: "default"},
+		{value: "/shop", wantRepo: "shop", wantRule: "default"},
+		{value: "acme/", wantRepo: "shop", wantRule: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			api := newTestAPI(t, annotatedDeployment("shop-v2", map[string]string{workload.RepoAnnotation: tt.value}))
+			mapper, err := NewMapper(&config.Config{WorkloadAnnotations: true}, api)
+			if err != nil {
+				t.Fatalf("NewMapper() error = %v", err)
+			}
+
+			result, err := mapper.Map(context.Background(), "apps", "shop-v2")
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if result.Owner != tt.wantOwner || result.Repo != tt.wantRepo || result.Rule != tt.wantRule {
+				t.Errorf("Map() = %s/%s by %s, want %s/%s by %s", result.Owner, result.Repo, result.Rule, tt.wantOwner, tt.wantRepo, tt.wantRule)
+			}
+			if tt.wantRule == "default" {
+				want := "annotation github.com/repo: no match, invalid repository " + tt.value + ", expected name or owner/name"
+				if !slices.Contains(result.Trace, want) {
+					t.Errorf("Trace = %q, want %q", result.Trace, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMapRejectsInvalidRepositoriesOfAnyRule(t *testing.T) {
+	mapper := NewMapperWithRules(nil, []Rule{
+		&regexRule{re: regexp.MustCompile(`^(.*)-api$`), template: "acme/$1/api"},
+		defaultRule{},
+	})
+
+	result, err := mapper.Map(context.Background(), "apps", "shop-api")
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if result.Repo != "shop-api" || result.Rule != "default" {
+		t.Errorf("Map() = %s by %s, want shop-api by default", result.Repo, result.Rule)
+	}
+	want := "match ^(.*)-api$: no match, invalid repository acme/shop/api, expected name or owner/name"
+	if len(result.Trace) == 0 || result.Trace[0] != want {
+		t.Errorf("Trace = %q, want first %q", result.Trace, want)
+	}
+}
+
+func TestMapReadsWorkloadAnnotations(t *testing.T) {
+	api := newTestAPI(t,
+		annotatedDeployment("shop", map[string]string{
+			workload.RepoAnnotation:        "https://github.com/acme/storefront",
+			workload.EnvironmentAnnotation: "staging",
+		}),
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "apps",
+			Name:      "postgres",
+			Annotations: map[string]string{
+				workload.RepoAnnotation:        "acme/databases",
+				workload.EnvironmentAnnotation: "production-db",
+			},
+		}},
+		annotatedDeployment("cart", map[string]string{workload.EnvironmentAnnotation: "canary"}),
+	)
+	conf := &config.Config{
+		Owner:               "acme",
+		WorkloadAnnotations: true,
+		Repositories:        []config.Repository{{Name: "checkout", Workloads: []string{"cart"}}},
+	}
+	mapper, err := NewMapper(conf, api)
+	if err != nil {
+		t.Fatalf("NewMapper() error = %v", err)
+	}
+
+	tests := []struct {
+		workload                             string
+		wantOwner, wantRepo, wantEnvironment string
+		wantRule                             string
+	}{
+		{workload: "shop", wantOwner: "acme", wantRepo: "storefront", wantEnvironment: "staging", wantRule: "annotation github.com/repo"},
+		{workload: "postgres", wantOwner: "acme", wantRepo: "databases", wantEnvironment: "production-db", wantRule: "annotation github.com/repo"},
+		{workload: "cart", wantOwner: "acme", wantRepo: "checkout", wantEnvironment: "canary", wantRule: "workload cart"},
+		{workload: "reviews-v2", wantRepo: "reviews", wantRule: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.workload, func(t *testing.T) {
+			result, err := mapper.Map(context.Background(), "apps", tt.workload)
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if result.Owner != tt.wantOwner || result.Repo != tt.wantRepo || result.Rule != tt.wantRule {
+				t.Errorf("Map() = %s/%s by %s, want %s/%s by %s", result.Owner, result.Repo, result.Rule, tt.wantOwner, tt.wantRepo, tt.wantRule)
+			}
+			if result.Environment != tt.wantEnvironment {
+				t.Errorf("Environment = %q, want %q", result.Environment, tt.wantEnvironment)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package mapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+// errInvalidRepository is returned for repositories which are neither name nor owner/name,
+// the mapper continues with the next rule
+var errInvalidRepository = errors.New("invalid repository")
+
+// versionSuffix is dropped from workload names by the default rule, e.g. reviews-v2 is deployed from reviews
+var versionSuffix = regexp.MustCompile(`-v\d.*`)
+
+// explicitRule maps one workload, given as name or namespace/name, to its repository
+type explicitRule struct {
+	workload string
+	repo     string
+}
+
+func (r *explicitRule) Map(_ context.Context, t *Target) (string, bool, error) {
+	if r.workload != t.Name && r.workload != t.Namespace+"/"+t.Name {
+		return "", false, nil
+	}
+	return r.repo, true, nil
+}
+
+func (r *explicitRule) String() string {
+	return fmt.Sprintf("workload %s", r.workload)
+}
+
+// regexRule maps the workloads whose names match re to template expanded with the capture groups of the match
+type regexRule struct {
+	re       *regexp.Regexp
+	template string
+}
+
+func (r *regexRule) Map(_ context.Context, t *Target) (string, bool, error) {
+	match := r.re.FindStringSubmatchIndex(t.Name)
+	if match == nil {
+		return "", false, nil
+	}
+	repo := string(r.re.ExpandString(nil, r.template, t.Name, match))
+	return repo, repo != "", nil
+}
+
+func (r *regexRule) String() string {
+	return fmt.Sprintf("match %s", r.re)
+}
+
+// metadataRule maps workloads to the value of a label, or an annotation, of their Deployment or StatefulSet.
+// The value is a repository, owner/repository or the URL of the repository.
+type metadataRule struct {
+	key        string
+	annotation bool
+}
+
+func (r *metadataRule) Map(_ context.Context, t *Target) (string, bool, error) {
+	w, err := t.Object()
+	if errors.Is(err, model.ErrWorkloadNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	values := w.Labels
+	if r.annotation {
+		values = w.Annotations
+	}
+	repo, ok := values[r.key]
+	if !ok || repo == "" {
+		return "", false, nil
+	}
+	if owner, name, isURL := model.ParseRepoURL(repo); isURL {
+		repo = owner + "/" + name
+	}
+	if err := checkRepo(repo); err != nil {
+		return "", false, err
+	}
+	return repo, true, nil
+}
+
+func (r *metadataRule) String() string {
+	if r.annotation {
+		return fmt.Sprintf("annotation %s", r.key)
+	}
+	return fmt.Sprintf("label %s", r.key)
+}
+
+// defaultRule maps workloads to the repository named like the workload without a version suffix
+type defaultRule struct{}
+
+func (defaultRule) Map(_ context.Context, t *Target) (string, bool, error) {
+	repo := versionSuffix.ReplaceAllString(t.Name, "")
+	return repo, repo != "", nil
+}
+
+func (defaultRule) String() string {
+	return "default"
+}
+
+// checkRepo returns an error matching errInvalidRepository unless repo is name or owner/name
+func checkRepo(repo string) error {
+	parts := strings.Split(repo, "/")
+	if len(parts) > 2 || slices.Contains(parts, "") {
+		return fmt.Errorf("%w %s, expected name or owner/name", errInvalidRepository, repo)
+	}
+	return nil
+}
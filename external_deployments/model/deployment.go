@@ -14,6 +14,8 @@ type Deployment struct {
 	CreatedAt   time.Time `json:"created_at,omitempty"`
 	UpdatedAt   time.Time `json:"updated_at,omitempty"`
 	SucceededAt time.Time `json:"succeeded_at,omitempty"`
+	// Source is the provider the deployment was read from, set if deployments of several providers are merged
+	Source string `json:"source,omitempty"`
 
 	// commits
 	ComparisonURL string    `json:"comparison_url"`
@@ -29,6 +31,7 @@ type shadowDeployment struct {
 	CreatedAt   *time.Time `json:"created_at,omitempty"`
 	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
 	SucceededAt *time.Time `json:"succeeded_at,omitempty"`
+	Source      string     `json:"source,omitempty"`
 
 	// commits
 	ComparisonURL string    `json:"comparison_url"`
@@ -71,6 +74,7 @@ func (d Deployment) MarshalJSON() ([]byte, error) {
 		CreatedAt:     formatTime(d.CreatedAt),
 		UpdatedAt:     formatTime(d.UpdatedAt),
 		SucceededAt:   formatTime(d.SucceededAt),
+		Source:        d.Source,
 		ComparisonURL: d.ComparisonURL,
 		Added:         d.Added,
 		Removed:       d.Removed,
@@ -85,6 +89,51 @@ func (d Deployment) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UnmarshalJSON reads the JSON MarshalJSON writes, timestamps may be "" for zero timestamps
+func (d *Deployment) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID            int64     `json:"id"`
+		SHA           string    `json:"sha"`
+		CreatedAt     string    `json:"created_at"`
+		UpdatedAt     string    `json:"updated_at"`
+		SucceededAt   string    `json:"succeeded_at"`
+		Source        string    `json:"source"`
+		ComparisonURL string    `json:"comparison_url"`
+		Added         []*Commit `json:"added"`
+		Removed       []*Commit `json:"removed"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*d = Deployment{
+		ID:            raw.ID,
+		SHA:           raw.SHA,
+		Source:        raw.Source,
+		ComparisonURL: raw.ComparisonURL,
+		Added:         raw.Added,
+		Removed:       raw.Removed,
+	}
+	for _, ts := range []struct {
+		value  string
+		target *time.Time
+	}{
+		{raw.CreatedAt, &d.CreatedAt},
+		{raw.UpdatedAt, &d.UpdatedAt},
+		{raw.SucceededAt, &d.SucceededAt},
+	} {
+		if ts.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, ts.value)
+		if err != nil {
+			return err
+		}
+		*ts.target = t
+	}
+	return nil
+}
+
 type Commit struct {
 	SHA   string `json:"sha"`
 	Title string `json:"title"`
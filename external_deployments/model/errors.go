@@ -0,0 +1,56 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrRepositoryNotFound is returned by providers if the repository of a workload does not exist
+var ErrRepositoryNotFound = errors.New("repository not found")
+
+// ErrWorkloadNotFound is returned if the workload of a query does not exist in the cluster
+var ErrWorkloadNotFound = errors.New("workload not found")
+
+// ErrRateLimited is returned by providers if the rate limit of the provider's API is exhausted
+var ErrRateLimited = errors.New("rate limit exhausted")
+
+// RateLimitError is returned if the rate limit is exhausted and the provider does not wait for its reset.
+// It matches ErrRateLimited.
+type RateLimitError struct {
+	// Reset is the time the rate limit resets
+	Reset time.Time
+	// Secondary is set if a secondary rate limit, e.g. GitHub's limit of concurrent requests, was hit
+	Secondary bool
+}
+
+func (e *RateLimitError) Error() string {
+	kind := "rate limit"
+	if e.Secondary {
+		kind = "secondary rate limit"
+	}
+	return fmt.Sprintf("%s exhausted; resets at %v", kind, e.Reset.Format(time.RFC3339))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// PartialError is returned together with the deployments of the sources which succeeded
+// if other sources of a query failed
+type PartialError struct {
+	Errors []error
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("partial result, %d sources failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *PartialError) Unwrap() []error {
+	return e.Errors
+}
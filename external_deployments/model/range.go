@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// SplitRange returns the deployments which succeeded in range (from, to) and the newest deployment
+// which succeeded before from. The "one before" is the base the oldest deployment in range is compared to.
+//
+// successful has to be sorted by SucceededAt, newest first.
+func SplitRange(successful []*Deployment, from, to time.Time) ([]*Deployment, *Deployment) {
+	inRange := make([]*Deployment, 0, len(successful))
+	for _, d := range successful {
+		if d.SucceededAt.After(from) && d.SucceededAt.Before(to) {
+			inRange = append(inRange, d)
+		}
+	}
+
+	for _, d := range successful {
+		if d.SucceededAt.Before(from) {
+			return inRange, d
+		}
+	}
+	return inRange, nil
+}
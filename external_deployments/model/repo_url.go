@@ -0,0 +1,30 @@
+package model
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ParseRepoURL returns owner and name of a repository URL like https://github.com/owner/repo.git,
+// ssh://git@github.com/owner/repo or git@github.com:owner/repo.git, ok is false for other formats
+func ParseRepoURL(repoURL string) (owner, name string, ok bool) {
+	var path string
+	if rest, found := strings.CutPrefix(repoURL, "git@"); found {
+		_, path, found = strings.Cut(rest, ":")
+		if !found {
+			return "", "", false
+		}
+	} else {
+		u, err := url.Parse(repoURL)
+		if err != nil || u.Host == "" {
+			return "", "", false
+		}
+		path = u.Path
+	}
+
+	owner, name, found := strings.Cut(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if !found || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return owner, name, true
+}
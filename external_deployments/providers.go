@@ -0,0 +1,168 @@
+package external_deployments
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/argocd"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/composite"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/file"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/flux"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/git"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/gitea"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/github"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/gitlab"
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+// the built-in providers
+func init() {
+	RegisterProvider("github", newGitHubClient)
+	RegisterProvider("gitlab", newGitLabClient)
+	RegisterProvider("gitea", newGiteaClient)
+	RegisterProvider("argocd", newArgoCDClient)
+	RegisterProvider("flux", newFluxClient)
+	RegisterProvider("git", newGitClient)
+	RegisterProvider("file", newFileClient)
+	RegisterProvider("composite", newCompositeClient)
+}
+
+func newGitHubClient(conf *config.Config) (DeploymentClient, error) {
+	owner := conf.Owner
+	if len(owner) == 0 {
+		return nil, fmt.Errorf("external_service.external_deployments.auth.username not set in config")
+	}
+
+	ghAPI, err := github.NewAPI(conf)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("TEST") == "true" {
+		log.Info("using mock GitHub client")
+		ghAPI = github.NewMockAPI()
+	}
+	return github.NewDeploymentClient(ghAPI, conf)
+}
+
+func newGitLabClient(conf *config.Config) (DeploymentClient, error) {
+	glAPI, err := gitlab.NewAPI(conf)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("TEST") == "true" {
+		log.Info("using mock GitLab client")
+		glAPI = gitlab.NewMockAPI()
+	}
+	return gitlab.NewDeploymentClient(glAPI, conf)
+}
+
+func newGiteaClient(conf *config.Config) (DeploymentClient, error) {
+	gtAPI, err := gitea.NewAPI(conf)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("TEST") == "true" {
+		log.Info("using mock Gitea client")
+		gtAPI = gitea.NewMockAPI()
+	}
+	return gitea.NewDeploymentClient(gtAPI, conf)
+}
+
+func newArgoCDClient(conf *config.Config) (DeploymentClient, error) {
+	var settings argocd.Settings
+	if err := DecodeSettings(conf, "argocd", &settings); err != nil {
+		return nil, err
+	}
+	argoAPI, err := argocd.NewAPI(conf, settings)
+	if err != nil {
+		return nil, err
+	}
+	ghAPI, err := github.NewAPI(conf)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("TEST") == "true" {
+		log.Info("using mock Argo CD and GitHub clients")
+		argoAPI = argocd.NewMockAPI()
+		ghAPI = github.NewMockAPI()
+	}
+	comparer, err := github.NewDeploymentClient(ghAPI, conf)
+	if err != nil {
+		return nil, err
+	}
+	return argocd.NewDeploymentClient(argoAPI, comparer)
+}
+
+func newFluxClient(conf *config.Config) (DeploymentClient, error) {
+	var settings flux.Settings
+	if err := DecodeSettings(conf, "flux", &settings); err != nil {
+		return nil, err
+	}
+	fluxAPI, err := flux.NewAPI(conf, settings)
+	if err != nil {
+		return nil, err
+	}
+	ghAPI, err := github.NewAPI(conf)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("TEST") == "true" {
+		log.Info("using mock Flux and GitHub clients")
+		fluxAPI = flux.NewMockAPI()
+		ghAPI = github.NewMockAPI()
+	}
+	comparer, err := github.NewDeploymentClient(ghAPI, conf)
+	if err != nil {
+		return nil, err
+	}
+	return flux.NewDeploymentClient(fluxAPI, comparer, settings)
+}
+
+func newGitClient(conf *config.Config) (DeploymentClient, error) {
+	var settings git.Settings
+	if err := DecodeSettings(conf, "git", &settings); err != nil {
+		return nil, err
+	}
+	gitAPI, err := git.NewAPI()
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("TEST") == "true" {
+		log.Info("using mock git client")
+		gitAPI = git.NewMockAPI()
+	}
+	return git.NewDeploymentClient(gitAPI, conf, settings)
+}
+
+func newFileClient(conf *config.Config) (DeploymentClient, error) {
+	var settings file.Settings
+	if err := DecodeSettings(conf, "file", &settings); err != nil {
+		return nil, err
+	}
+	return file.NewDeploymentClient(conf, settings)
+}
+
+func newCompositeClient(conf *config.Config) (DeploymentClient, error) {
+	sources := make([]composite.Source, 0, len(conf.Providers))
+	for _, name := range conf.Providers {
+		if name == "composite" {
+			return nil, fmt.Errorf("composite provider cannot contain itself")
+		}
+		sourceConf := *conf
+		sourceConf.Provider = name
+		client, err := newProviderClient(&sourceConf)
+		if err != nil {
+			// release the stores of the sources created so far
+			for _, source := range sources {
+				if closer, ok := source.Client.(io.Closer); ok {
+					_ = closer.Close()
+				}
+			}
+			return nil, fmt.Errorf("error while creating provider %s: %w", name, err)
+		}
+		sources = append(sources, composite.Source{Name: name, Client: client})
+	}
+	return composite.NewDeploymentClient(sources)
+}
@@ -0,0 +1,76 @@
+package external_deployments
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+)
+
+// Factory creates the deployment client of a provider from the configuration
+type Factory func(conf *config.Config) (DeploymentClient, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// RegisterProvider makes a provider available by name for config.Config.Provider. Providers in other
+// packages register in their init function and are enabled by importing them. Providers with settings
+// beyond config.Config read them with DecodeSettings.
+//
+// It panics if a provider is registered twice or factory is nil.
+func RegisterProvider(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("external_deployments: factory of provider " + name + " is nil")
+	}
+	if _, ok := factories[name]; ok {
+		panic("external_deployments: provider " + name + " registered twice")
+	}
+	factories[name] = factory
+}
+
+// Providers returns the names of the registered providers, sorted
+func Providers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func lookupProvider(name string) (Factory, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("external deployments provider %s not supported, registered providers: %s",
+			name, strings.Join(Providers(), ", "))
+	}
+	return factory, nil
+}
+
+// DecodeSettings decodes the settings of the provider name in conf.ProviderSettings into v,
+// a pointer to the provider's settings struct. Unknown fields are rejected, v is left unchanged
+// if there are no settings for the provider.
+func DecodeSettings(conf *config.Config, name string, v any) error {
+	raw, ok := conf.ProviderSettings[name]
+	if !ok {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid settings of provider %s: %w", name, err)
+	}
+	return nil
+}
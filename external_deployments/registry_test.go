@@ -0,0 +1,159 @@
+package external_deployments
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+type stubClient struct{}
+
+func (stubClient) ListDeploymentsInRange(context.Context, models.DeploymentsQuery) ([]*model.Deployment, error) {
+	return nil, nil
+}
+
+// registerTestProvider registers a provider for the duration of the test
+func registerTestProvider(t *testing.T, name string, factory Factory) {
+	t.Helper()
+	RegisterProvider(name, factory)
+	t.Cleanup(func() {
+		factoriesMu.Lock()
+		defer factoriesMu.Unlock()
+		delete(factories, name)
+	})
+}
+
+func wantPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if msg, _ := r.(string); !strings.Contains(msg, want) {
+			t.Errorf("panic = %v, want %q", r, want)
+		}
+	}()
+	f()
+}
+
+func TestRegisterProvider(t *testing.T) {
+	var got *config.Config
+	registerTestProvider(t, "stub", func(conf *config.Config) (DeploymentClient, error) {
+		got = conf
+		return stubClient{}, nil
+	})
+
+	if !slices.Contains(Providers(), "stub") {
+		t.Errorf("Providers() = %v, want stub", Providers())
+	}
+	conf := &config.Config{Provider: "stub"}
+	client, err := newProviderClient(conf)
+	if err != nil {
+		t.Fatalf("newProviderClient() error = %v", err)
+	}
+	if _, ok := client.(stubClient); !ok || got != conf {
+		t.Errorf("newProviderClient() = %T, want the client of the stub factory", client)
+	}
+}
+
+func TestRegisterProviderTwicePanics(t *testing.T) {
+	factory := func(*config.Config) (DeploymentClient, error) { return stubClient{}, nil }
+	registerTestProvider(t, "stub", factory)
+
+	wantPanic(t, "provider stub registered twice", func() { RegisterProvider("stub", factory) })
+	wantPanic(t, "provider github registered twice", func() { RegisterProvider("github", factory) })
+}
+
+func TestRegisterProviderNilFactoryPanics(t *testing.T) {
+	wantPanic(t, "factory of provider stub is nil", func() { RegisterProvider("stub", nil) })
+	if slices.Contains(Providers(), "stub") {
+		t.Errorf("Providers() = %v, want the nil factory not registered", Providers())
+	}
+}
+
+func TestLookupProviderUnknown(t *testing.T) {
+	_, err := lookupProvider("jenkins")
+	want := "external deployments provider jenkins not supported, registered providers: " +
+		"argocd, composite, file, flux, git, gitea, github, gitlab"
+	if err == nil || err.Error() != want {
+		t.Errorf("lookupProvider() error = %v, want %q", err, want)
+	}
+}
+
+func TestDecodeSettings(t *testing.T) {
+	type settings struct {
+		URL  string   `json:"url"`
+		Jobs []string `json:"jobs"`
+	}
+	tests := []struct {
+		name     string
+		settings map[string]json.RawMessage
+		want     settings
+		wantErr  string
+	}{
+		{
+			name:     "decoded",
+			settings: map[string]json.RawMessage{"jenkins": json.RawMessage(`{"url":"https://jenkins.example.com","jobs":["deploy"]}`)},
+			want:     settings{URL: "https://jenkins.example.com", Jobs: []string{"deploy"}},
+		},
+		{
+			name:     "absent",
+			settings: map[string]json.RawMessage{"other": json.RawMessage(`{"url":"https://other.example.com"}`)},
+			want:     settings{URL: "default"},
+		},
+		{
+			name:     "unknown field",
+			settings: map[string]json.RawMessage{"jenkins": json.RawMessage(`{"urls":["https://jenkins.example.com"]}`)},
+			want:     settings{URL: "default"},
+			wantErr:  `invalid settings of provider jenkins: json: unknown field "urls"`,
+		},
+		{
+			name:     "invalid type",
+			settings: map[string]json.RawMessage{"jenkins": json.RawMessage(`{"jobs":"deploy"}`)},
+			want:     settings{URL: "default"},
+			wantErr:  "invalid settings of provider jenkins",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := settings{URL: "default"}
+			err := DecodeSettings(&config.Config{ProviderSettings: tt.settings}, "jenkins", &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("DecodeSettings() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeSettings() error = %v", err)
+			}
+			if got.URL != tt.want.URL || !slices.Equal(got.Jobs, tt.want.Jobs) {
+				t.Errorf("settings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltInProviderReadsSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deployments.json")
+	if err := os.WriteFile(path, []byte(`[{"id": 1, "repository": "shop", "sha": "a1", "succeeded_at": "2026-03-18T10:00:00Z"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{Provider: "file", ProviderSettings: map[string]json.RawMessage{"file": json.RawMessage(`{"path":"` + path + `"}`)}}
+	if _, err := newProviderClient(conf); err != nil {
+		t.Errorf("newProviderClient() error = %v", err)
+	}
+
+	conf.ProviderSettings["file"] = json.RawMessage(`{"file":"` + path + `"}`)
+	if _, err := newProviderClient(conf); err == nil || !strings.Contains(err.Error(), `unknown field "file"`) {
+		t.Errorf("newProviderClient() error = %v, want the unknown setting rejected", err)
+	}
+}
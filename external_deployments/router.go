@@ -0,0 +1,90 @@
+package external_deployments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/models"
+)
+
+// routingClient sends the queries of configured repositories with their own provider to the client
+// of that provider, all other queries to the client of the default provider
+type routingClient struct {
+	conf          *config.Config
+	defaultClient DeploymentClient
+	clients       map[string]DeploymentClient
+}
+
+// newRoutingClient creates a client per provider of conf.Repositories, it returns the client of conf.Provider
+// if no repository has another provider
+func newRoutingClient(conf *config.Config) (DeploymentClient, error) {
+	defaultClient, err := newProviderClient(conf)
+	if err != nil {
+		return nil, err
+	}
+	rc := &routingClient{
+		conf:          conf,
+		defaultClient: defaultClient,
+		clients:       map[string]DeploymentClient{conf.Provider: defaultClient},
+	}
+
+	for _, repo := range conf.Repositories {
+		if repo.Provider == "" {
+			continue
+		}
+		if _, ok := rc.clients[repo.Provider]; ok {
+			continue
+		}
+		providerConf := *conf
+		providerConf.Provider = repo.Provider
+		client, err := newProviderClient(&providerConf)
+		if err != nil {
+			_ = rc.Close()
+			return nil, fmt.Errorf("error while creating provider %s of repository %s/%s: %w", repo.Provider, conf.OwnerOf(&repo), repo.Name, err)
+		}
+		rc.clients[repo.Provider] = client
+	}
+
+	if len(rc.clients) == 1 {
+		return defaultClient, nil
+	}
+	return rc, nil
+}
+
+// clientOf returns the client of the provider of owner/repo
+func (rc *routingClient) clientOf(owner, repo string) DeploymentClient {
+	if r := rc.conf.Repository(owner, repo); r != nil && r.Provider != "" {
+		return rc.clients[r.Provider]
+	}
+	return rc.defaultClient
+}
+
+func (rc *routingClient) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
+	return rc.clientOf(q.Owner, q.Repo).ListDeploymentsInRange(ctx, q)
+}
+
+// InvalidateCache drops the cached data of owner/repo in the client of its provider, if it caches any
+func (rc *routingClient) InvalidateCache(ctx context.Context, owner, repo string) error {
+	invalidator, ok := rc.clientOf(owner, repo).(CacheInvalidator)
+	if !ok {
+		return nil
+	}
+	return invalidator.InvalidateCache(ctx, owner, repo)
+}
+
+// Close closes the clients holding resources
+func (rc *routingClient) Close() error {
+	var errs []error
+	for provider, client := range rc.clients {
+		if closer, ok := client.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
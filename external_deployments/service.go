@@ -2,23 +2,51 @@ package external_deployments
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/mapping"
 	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/workload"
+	"github.com/kemonprogrammer/github-go-client/log"
 	"github.com/kemonprogrammer/github-go-client/models"
 )
 
 type DeploymentService struct {
 	deploymentClientInterface DeploymentClient
 	conf                      *config.Config
+	mapper                    *mapping.Mapper
 }
 
 func NewDeploymentService(conf *config.Config, client DeploymentClient) (*DeploymentService, error) {
+	var workloadAPI workload.API
+	readsWorkloads := slices.ContainsFunc(conf.WorkloadMapping, func(rule config.MappingRule) bool {
+		return rule.Label != "" || rule.Annotation != ""
+	})
+	if conf.WorkloadAnnotations || readsWorkloads {
+		var err error
+		workloadAPI, err = workload.NewAPI(conf)
+		if err != nil {
+			return nil, err
+		}
+		if os.Getenv("TEST") == "true" {
+			log.Info("using mock workload client")
+			workloadAPI = workload.NewMockAPI()
+		}
+	}
+	mapper, err := mapping.NewMapper(conf, workloadAPI)
+	if err != nil {
+		return nil, err
+	}
 
 	return &DeploymentService{
 		deploymentClientInterface: client,
 		conf:                      conf,
+		mapper:                    mapper,
 	}, nil
 }
 
@@ -32,37 +60,72 @@ func (in *DeploymentService) client() (DeploymentClient, error) {
 
 	return in.deploymentClientInterface, nil
 }
+
+// ListDeploymentsInRange routes q to the repository of its workload.
+// If q.Repo is empty the repository is mapped from q.Workload by the first matching mapping rule.
+// If q.Owner is empty the configured owner is used. Unless q.Environment is set the environment is the one
+// annotated on the workload, or else the one of a configured repository, or else the configured one.
+// If some providers failed the deployments of the others are returned with a *model.PartialError.
 func (in *DeploymentService) ListDeploymentsInRange(ctx context.Context, q models.DeploymentsQuery) ([]*model.Deployment, error) {
 	client, err := in.client()
 	if err != nil {
 		return nil, err
 	}
 
+	if q.Repo == "" {
+		result, err := in.mapper.Map(ctx, q.Namespace, q.Workload)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("workload %s/%s mapped to repository %s by %s, trace: %s", q.Namespace, q.Workload, result.Repo, result.Rule, strings.Join(result.Trace, "; "))
+		q.Repo = result.Repo
+		if q.Owner == "" {
+			q.Owner = result.Owner
+		}
+		if q.Environment == "" {
+			q.Environment = result.Environment
+		}
+	}
+	if q.Owner == "" {
+		q.Owner = in.conf.Owner
+	}
+	if repo := in.conf.Repository(q.Owner, q.Repo); repo != nil && q.Environment == "" {
+		q.Environment = repo.Environment
+	}
+
 	//var end observability.EndFunc
 	//ctx, end = observability.StartSpan(ctx, "ListDeploymentsInRange",
 	//	observability.Attribute("package", "external_deployments"),
 	//	//observability.Attribute(observability.TracingClusterTag, query.Cluster),
 	//	observability.Attribute("cluster", q.Cluster),
 	//	observability.Attribute("namespace", q.Namespace),
-	//	observability.Attribute("repository", client.GetRepo()),
+	//	observability.Attribute("repository", q.Repo),
 	//)
 	//defer end()
 
-	deployments, err := client.ListDeploymentsInRange(ctx, q.From, q.To)
-	if err != nil {
+	deployments, err := client.ListDeploymentsInRange(ctx, q)
+	var partialErr *model.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
 		return nil, err
 	}
-	return deployments, nil
+	// partial results are returned with their error
+	return deployments, err
 }
 
-func (in *DeploymentService) SetRepo(ctx context.Context, repo string) error {
+// InvalidateCache drops the cached data of owner/repo, if the client caches any.
+// If owner is empty the configured owner is used.
+func (in *DeploymentService) InvalidateCache(ctx context.Context, owner, repo string) error {
 	client, err := in.client()
 	if err != nil {
 		return err
 	}
-	err = client.SetRepo(ctx, repo)
-	if err != nil {
-		return err
+	if owner == "" {
+		owner = in.conf.Owner
+	}
+
+	invalidator, ok := client.(CacheInvalidator)
+	if !ok {
+		return nil
 	}
-	return nil
+	return invalidator.InvalidateCache(ctx, owner, repo)
 }
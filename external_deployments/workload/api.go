@@ -0,0 +1,83 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+// API mock for testing
+type API interface {
+	GetWorkload(ctx context.Context, namespace, name string) (*Workload, error)
+}
+
+type Client struct {
+	client kubernetes.Interface
+}
+
+// NewAPI connects to the cluster of conf.KubeConfig, or the cluster it runs in if no kubeconfig is set
+func NewAPI(conf *config.Config) (API, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", conf.KubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error while loading kubernetes config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating kubernetes client: %w", err)
+	}
+	return NewWorkloadClient(client)
+}
+
+// NewWorkloadClient reads workloads with client, e.g. a fake clientset in tests
+func NewWorkloadClient(client kubernetes.Interface) (API, error) {
+	if client == nil {
+		return nil, fmt.Errorf("kubernetes client cannot be nil")
+	}
+	return &Client{
+		client: client,
+	}, nil
+}
+
+// GetWorkload returns the Deployment namespace/name, or the StatefulSet if there is no such Deployment
+func (wc *Client) GetWorkload(ctx context.Context, namespace, name string) (*Workload, error) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("getWorkload took %v\n", time.Since(start))
+	}()
+
+	deployment, err := wc.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return toWorkload("Deployment", deployment.ObjectMeta), nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error while getting deployment %s/%s: %w", namespace, name, err)
+	}
+
+	statefulSet, err := wc.client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return toWorkload("StatefulSet", statefulSet.ObjectMeta), nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error while getting stateful set %s/%s: %w", namespace, name, err)
+	}
+	return nil, fmt.Errorf("%w: no deployment or stateful set %s/%s", model.ErrWorkloadNotFound, namespace, name)
+}
+
+func toWorkload(kind string, meta metav1.ObjectMeta) *Workload {
+	return &Workload{
+		Kind:        kind,
+		Namespace:   meta.Namespace,
+		Name:        meta.Name,
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+	}
+}
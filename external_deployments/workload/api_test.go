@@ -0,0 +1,95 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+)
+
+func newTestClient(t *testing.T, clientset *fake.Clientset) API {
+	t.Helper()
+	api, err := NewWorkloadClient(clientset)
+	if err != nil {
+		t.Fatalf("NewWorkloadClient() error = %v", err)
+	}
+	return api
+}
+
+func TestGetWorkload(t *testing.T) {
+	clientset := fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "apps",
+			Name:        "shop",
+			Labels:      map[string]string{"app.kubernetes.io/part-of": "shop"},
+			Annotations: map[string]string{RepoAnnotation: "acme/shop"},
+		}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "apps",
+			Name:        "shop",
+			Annotations: map[string]string{RepoAnnotation: "acme/shop-db"},
+		}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "apps",
+			Name:        "postgres",
+			Annotations: map[string]string{RepoAnnotation: "acme/postgres"},
+		}},
+	)
+	api := newTestClient(t, clientset)
+
+	tests := []struct {
+		namespace, name string
+		wantKind        string
+		wantRepo        string
+	}{
+		{namespace: "apps", name: "shop", wantKind: "Deployment", wantRepo: "acme/shop"},
+		{namespace: "apps", name: "postgres", wantKind: "StatefulSet", wantRepo: "acme/postgres"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := api.GetWorkload(context.Background(), tt.namespace, tt.name)
+			if err != nil {
+				t.Fatalf("GetWorkload() error = %v", err)
+			}
+			if w.Kind != tt.wantKind || w.Namespace != tt.namespace || w.Name != tt.name {
+				t.Errorf("GetWorkload() = %s %s/%s, want %s %s/%s", w.Kind, w.Namespace, w.Name, tt.wantKind, tt.namespace, tt.name)
+			}
+			if got := w.Annotations[RepoAnnotation]; got != tt.wantRepo {
+				t.Errorf("annotation %s = %q, want %q", RepoAnnotation, got, tt.wantRepo)
+			}
+		})
+	}
+}
+
+func TestGetWorkloadNotFound(t *testing.T) {
+	api := newTestClient(t, fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "shop"}},
+	))
+
+	_, err := api.GetWorkload(context.Background(), "apps", "shop")
+	if !errors.Is(err, model.ErrWorkloadNotFound) {
+		t.Errorf("GetWorkload() error = %v, want %v", err, model.ErrWorkloadNotFound)
+	}
+}
+
+func TestGetWorkloadFailsOnOtherErrors(t *testing.T) {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("get", "statefulsets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "statefulsets"}, "shop", errors.New("no access"))
+	})
+	api := newTestClient(t, clientset)
+
+	_, err := api.GetWorkload(context.Background(), "apps", "shop")
+	if err == nil || errors.Is(err, model.ErrWorkloadNotFound) || !apierrors.IsForbidden(err) {
+		t.Errorf("GetWorkload() error = %v, want the forbidden error of the stateful set", err)
+	}
+}
@@ -0,0 +1,35 @@
+package workload
+
+import (
+	"context"
+	"time"
+)
+
+type MockWorkloadClient struct {
+	partOf string
+	repo   string
+}
+
+func NewMockAPI() API {
+	return &MockWorkloadClient{
+		partOf: "mock-repo",
+		repo:   "https://github.com/mock-owner/mock-repo",
+	}
+}
+
+func (wc *MockWorkloadClient) GetWorkload(_ context.Context, namespace, name string) (*Workload, error) {
+	time.Sleep(50 * time.Millisecond)
+
+	return &Workload{
+		Kind:      "Deployment",
+		Namespace: namespace,
+		Name:      name,
+		Labels: map[string]string{
+			"app.kubernetes.io/name":    name,
+			"app.kubernetes.io/part-of": wc.partOf,
+		},
+		Annotations: map[string]string{
+			RepoAnnotation: wc.repo,
+		},
+	}, nil
+}
@@ -0,0 +1,17 @@
+package workload
+
+const (
+	// RepoAnnotation is the repository a workload is deployed from, as repository, owner/repository or URL
+	RepoAnnotation = "github.com/repo"
+	// EnvironmentAnnotation is the environment of the deployments of a workload
+	EnvironmentAnnotation = "github.com/environment"
+)
+
+// Workload is the metadata of a Deployment or StatefulSet
+type Workload struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
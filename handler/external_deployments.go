@@ -2,96 +2,199 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/kemonprogrammer/github-go-client/config"
 	"github.com/kemonprogrammer/github-go-client/external_deployments"
 	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
 	"github.com/kemonprogrammer/github-go-client/models"
+	"github.com/kemonprogrammer/github-go-client/secret"
 )
 
-func HttpHandler(ctx context.Context, conf *config.Config, workload string) (*DeploymentResponse, error) {
-	repo := extractRepoName(workload)
+// defaultRange is used for the query window if no "from" parameter is given
+const defaultRange = time.Hour
 
-	deploymentClient, err := external_deployments.NewDeploymentClient(conf)
-	if err != nil {
-		return nil, err
+type DeploymentResponse struct {
+	Deployments []*model.Deployment `json:"deployments"`
+	Total       int                 `json:"total"`
+	// Warnings lists the failed providers of a partial result
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type Params struct {
+	From, To time.Time
+}
+
+// DeploymentsHandler serves the deployments of a workload over HTTP
+type DeploymentsHandler struct {
+	service *external_deployments.DeploymentService
+	// adminToken authorizes dropping caches, the cache routes are not registered if it is nil
+	adminToken secret.Secret
+}
+
+// NewDeploymentsHandler creates the handler of service, adminToken is the bearer token required
+// to drop caches, nil to not serve the cache routes
+func NewDeploymentsHandler(service *external_deployments.DeploymentService, adminToken secret.Secret) *DeploymentsHandler {
+	return &DeploymentsHandler{
+		service:    service,
+		adminToken: adminToken,
 	}
-	deploymentService, err := external_deployments.NewDeploymentService(conf, deploymentClient)
-	if err != nil {
-		return nil, err
-	}
-
-	owner := conf.Owner
-
-	// params
-	if err := deploymentService.SetRepo(ctx, repo); err != nil {
-		fmt.Println(err)
-		fmt.Println(fmt.Errorf("no repository found for workload %s", workload))
-	}
-
-	fmt.Printf("owner: %s\n", owner)
-
-	//queryFrom := os.Getenv("FROM")
-	//queryTo := os.Getenv("TO")
-	//
-	//params, err := fillParams(queryFrom, queryTo)
-	//if err != nil {
-	//	fmt.Println(err)
-	//	return
-	//}
-	//
-	//deployments, err := deploymentService.ListDeploymentsInRange(ctx, params.From, params.To)
-	//if err != nil {
-	//	fmt.Println(err)
-	//	return
-	//}
-	//fmt.Printf("len deploys: %d\n", len(deployments))
-	//fmt.Printf("deployments response: %+v\n", deployments)
-
-	from, err := time.Parse(time.RFC3339, "2026-03-18T02:00:00+01:00")
-	to, err := time.Parse(time.RFC3339, "2026-03-18T03:00:00+01:00")
-	//from = time.Now().Add(-10 * time.Minute)
-	//to = time.Now()
+}
+
+// Register adds the routes of the handler to mux
+func (h *DeploymentsHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /namespaces/{namespace}/workloads/{workload}/deployments", h.ListDeployments)
+	if h.adminToken != nil {
+		mux.HandleFunc("DELETE /repositories/{owner}/{repo}/cache", h.InvalidateCache)
+	}
+}
+
+// ListDeployments handles GET /namespaces/{namespace}/workloads/{workload}/deployments?from=&to=
+//
+// from and to are RFC3339 timestamps. to defaults to now, from defaults to one hour before to.
+func (h *DeploymentsHandler) ListDeployments(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer func() {
+		log.Tracef("%s %s took %v", r.Method, r.URL.Path, time.Since(start))
+	}()
+
+	params, err := fillParams(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
 	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		writeError(w, http.StatusBadRequest, err)
+		return
 	}
 
 	q := models.DeploymentsQuery{
-		From:      from,
-		To:        to,
-		Cluster:   "",
-		Namespace: "",
-		Workload:  "",
+		From:      params.From,
+		To:        params.To,
+		Cluster:   r.URL.Query().Get("cluster"),
+		Namespace: r.PathValue("namespace"),
+		Workload:  r.PathValue("workload"),
 	}
 
-	deployments, err := deploymentService.ListDeploymentsInRange(ctx, q)
+	resp, err := h.listDeployments(r.Context(), q)
 	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		var rateLimitErr *model.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(time.Until(rateLimitErr.Reset).Seconds()))))
+		}
+		writeError(w, statusCode(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// InvalidateCache handles DELETE /repositories/{owner}/{repo}/cache, authorized by the admin token as bearer token
+func (h *DeploymentsHandler) InvalidateCache(w http.ResponseWriter, r *http.Request) {
+	ok, err := h.authorized(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("error while reading admin token: %w", err))
+		return
+	}
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid admin token"))
+		return
 	}
-	return &DeploymentResponse{Deployments: deployments}, nil
 
+	if err := h.service.InvalidateCache(r.Context(), r.PathValue("owner"), r.PathValue("repo")); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
-type DeploymentResponse struct {
-	Deployments []*model.Deployment `json:"deployments"`
+// authorized reports if r carries the admin token as bearer token
+func (h *DeploymentsHandler) authorized(r *http.Request) (bool, error) {
+	if h.adminToken == nil {
+		return false, nil
+	}
+	token, err := h.adminToken.Value()
+	if err != nil {
+		return false, err
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1, nil
 }
 
-func extractRepoName(workload string) string {
-	regexStr := "-v\\d.*"
-	r, err := regexp.Compile(regexStr)
+func (h *DeploymentsHandler) listDeployments(ctx context.Context, q models.DeploymentsQuery) (*DeploymentResponse, error) {
+	deployments, err := h.service.ListDeploymentsInRange(ctx, q)
+	var partialErr *model.PartialError
+	if errors.As(err, &partialErr) {
+		warnings := make([]string, len(partialErr.Errors))
+		for i, e := range partialErr.Errors {
+			warnings[i] = log.Redact(e.Error())
+		}
+		return &DeploymentResponse{Deployments: deployments, Total: len(deployments), Warnings: warnings}, nil
+	}
 	if err != nil {
-		fmt.Println(err)
-		return ""
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	return &DeploymentResponse{Deployments: deployments, Total: len(deployments)}, nil
+}
+
+// statusCode maps errors of the deployments provider to HTTP status codes
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, model.ErrRepositoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Errorf("error while writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Errorf("%v", err)
 	}
-	match, _ := regexp.MatchString(regexStr, workload)
-	repoName := workload
-	if match {
-		repoName = r.ReplaceAllString(workload, "")
+	writeJSON(w, status, ErrorResponse{Error: log.Redact(err.Error())})
+}
+
+func fillParams(from, to string) (*Params, error) {
+	dateTo := time.Now()
+	if to != "" {
+		var err error
+		dateTo, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse date to %s, %w", to, err)
+		}
+	}
+
+	dateFrom := dateTo.Add(-defaultRange)
+	if from != "" {
+		var err error
+		dateFrom, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse date from %s, %w", from, err)
+		}
+	}
+
+	if dateFrom.After(dateTo) {
+		return nil, fmt.Errorf("from %s is after to %s", dateFrom.Format(time.RFC3339), dateTo.Format(time.RFC3339))
 	}
-	return repoName
+	return &Params{
+		From: dateFrom,
+		To:   dateTo,
+	}, nil
 }
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kemonprogrammer/github-go-client/config"
+	"github.com/kemonprogrammer/github-go-client/external_deployments"
+	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/log"
+	"github.com/kemonprogrammer/github-go-client/models"
+	"github.com/kemonprogrammer/github-go-client/secret"
+)
+
+// fakeClient fails listing deployments with err and records the invalidated repositories
+type fakeClient struct {
+	err         error
+	invalidated []string
+}
+
+func (f *fakeClient) ListDeploymentsInRange(context.Context, models.DeploymentsQuery) ([]*model.Deployment, error) {
+	return nil, f.err
+}
+
+func (f *fakeClient) InvalidateCache(_ context.Context, owner, repo string) error {
+	f.invalidated = append(f.invalidated, owner+"/"+repo)
+	return nil
+}
+
+func newTestMux(t *testing.T, client external_deployments.DeploymentClient, adminToken secret.Secret) *http.ServeMux {
+	t.Helper()
+	service, err := external_deployments.NewDeploymentService(&config.Config{Enabled: true, Owner: "o"}, client)
+	if err != nil {
+		t.Fatalf("NewDeploymentService() error = %v", err)
+	}
+	mux := http.NewServeMux()
+	NewDeploymentsHandler(service, adminToken).Register(mux)
+	return mux
+}
+
+func TestInvalidateCacheRequiresAdminToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    secret.Secret
+		authorization string
+		wantStatus    int
+	}{
+		{name: "no admin token configured", authorization: "Bearer ", wantStatus: http.StatusNotFound},
+		{name: "missing token", adminToken: secret.Static("admin-secret"), wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", adminToken: secret.Static("admin-secret"), authorization: "Bearer other", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", adminToken: secret.Static("admin-secret"), authorization: "admin-secret", wantStatus: http.StatusUnauthorized},
+		{name: "admin token", adminToken: secret.Static("admin-secret"), authorization: "Bearer admin-secret", wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{}
+			mux := newTestMux(t, client, tt.adminToken)
+
+			req := httptest.NewRequest(http.MethodDelete, "/repositories/o/r/cache", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			wantInvalidated := 0
+			if tt.wantStatus == http.StatusNoContent {
+				wantInvalidated = 1
+			}
+			if len(client.invalidated) != wantInvalidated {
+				t.Errorf("invalidated = %v, want %d invalidation", client.invalidated, wantInvalidated)
+			}
+		})
+	}
+}
+
+func TestErrorsRedactRotatedTokenFile(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("ghp_firstTokenValue"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	token, err := secret.NewFile(path)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+
+	client := &fakeClient{}
+	mux := newTestMux(t, client, nil)
+	var bodies []string
+	for i, rotated := range []string{"ghp_rotatedTokenValue", ""} {
+		value, err := token.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		if want := []string{"ghp_firstTokenValue", "ghp_rotatedTokenValue"}[i]; value != want {
+			t.Fatalf("Value() = %q, want %q", value, want)
+		}
+		log.Errorf("request with token %s failed", value)
+
+		tokenErr := fmt.Errorf("GET https://api.github.com/repos/o/shop/deployments?access_token=%s: 401 Bad credentials", value)
+		for _, err := range []error{tokenErr, &model.PartialError{Errors: []error{tokenErr}}} {
+			client.err = err
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/namespaces/apps/workloads/shop/deployments", nil))
+			bodies = append(bodies, rec.Body.String())
+		}
+
+		if rotated != "" {
+			if err := os.WriteFile(path, []byte(rotated), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if !strings.Contains(logs.String(), "[REDACTED]") {
+		t.Errorf("logs = %q, want redacted tokens", logs.String())
+	}
+	for _, value := range []string{"ghp_firstTokenValue", "ghp_rotatedTokenValue"} {
+		if strings.Contains(logs.String(), value) {
+			t.Errorf("logs contain token %q: %s", value, logs.String())
+		}
+		for _, body := range bodies {
+			if strings.Contains(body, value) {
+				t.Errorf("response contains token %q: %s", value, body)
+			}
+		}
+	}
+}
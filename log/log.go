@@ -25,7 +25,7 @@ func Infof(format string, args ...any) {
 
 // Info logs at LevelInfo with printf-style formatting.
 func Info(msg string) {
-	log(context.Background(), slog.LevelInfo, msg)
+	log(context.Background(), slog.LevelInfo, "%s", msg)
 }
 
 // Warnf logs at LevelWarn with printf-style formatting.
@@ -62,7 +62,7 @@ func log(ctx context.Context, level slog.Level, format string, args ...any) {
 		msg = format
 	}
 
-	// 3. Create the record with the captured PC.
-	r := slog.NewRecord(time.Now(), level, msg, pc)
+	// 3. Create the record with the captured PC, secrets never reach a handler.
+	r := slog.NewRecord(time.Now(), level, Redact(msg), pc)
 	_ = logger.Handler().Handle(ctx, r)
 }
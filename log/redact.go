@@ -0,0 +1,79 @@
+package log
+
+import (
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// minSecretLength keeps short values, e.g. empty or placeholder tokens, from redacting common words
+	minSecretLength = 8
+	// maxSecrets bounds the redacted secrets, rotated and expired secrets are dropped oldest first
+	maxSecrets = 256
+)
+
+var (
+	secretsMu sync.Mutex
+	secrets   []string
+	replacer  atomic.Pointer[strings.Replacer]
+)
+
+// the standard logger, which slog writes to by default, redacts secrets as well
+func init() {
+	stdlog.SetOutput(NewRedactingWriter(os.Stderr))
+}
+
+// AddSecret makes Redact and all log output replace s with [REDACTED]
+func AddSecret(s string) {
+	if len(s) < minSecretLength {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, secret := range secrets {
+		if secret == s {
+			return
+		}
+	}
+	secrets = append(secrets, s)
+	if len(secrets) > maxSecrets {
+		secrets = secrets[len(secrets)-maxSecrets:]
+	}
+
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, redacted)
+	}
+	replacer.Store(strings.NewReplacer(pairs...))
+}
+
+// Redact replaces the secrets added with AddSecret in s
+func Redact(s string) string {
+	r := replacer.Load()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter returns a writer redacting the secrets added with AddSecret before writing to w.
+// Secrets are only redacted within a single write.
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -3,45 +3,24 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"expvar"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/kemonprogrammer/github-go-client/config"
-	"github.com/kemonprogrammer/github-go-client/external_deployments/model"
+	"github.com/kemonprogrammer/github-go-client/external_deployments"
 	"github.com/kemonprogrammer/github-go-client/handler"
+	"github.com/kemonprogrammer/github-go-client/secret"
 )
 
-type Response struct {
-	Deployments []*model.Deployment `json:"deployments"`
-	Size        int                 `json:"total"`
-}
-
-type Params struct {
-	From, To time.Time
-}
-
-func fillParams(from, to string) (*Params, error) {
-	//dateTimeFormat := "2006-01-02T00:00:00Z"
-	dateFrom, err := time.Parse(time.RFC3339, from)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't parse date from %s, %w", from, err)
-	}
-	dateTo, err := time.Parse(time.RFC3339, to)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't parse date to %s, %w", from, err)
-	}
-	params := &Params{
-		From: dateFrom,
-		To:   dateTo,
-	}
-	//fmt.Printf("params: %v", params)
-	return params, nil
-}
-
 func loadExampleRunsCache() (map[int64]time.Time, error) {
 	list := []string{
 		"22008656798", "2026-02-14T15:43:15Z",
@@ -97,69 +76,204 @@ func main() {
 	//}
 	cfg := SetupConfig()
 
-	workload := os.Getenv("WORKLOAD")
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
 
-	wg := sync.WaitGroup{}
-	var newerDeployments []*model.Deployment
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		start := time.Now()
+	deploymentClient, err := external_deployments.NewDeploymentClient(cfg)
+	if err != nil {
+		log.Fatalf("error while creating deployment client: %v", err)
+	}
+	if closer, ok := deploymentClient.(io.Closer); ok {
+		defer closer.Close()
+	}
+	deploymentService, err := external_deployments.NewDeploymentService(cfg, deploymentClient)
+	if err != nil {
+		log.Fatalf("error while creating deployment service: %v", err)
+	}
 
-		resp, err := handler.HttpHandler(context.Background(), cfg, workload)
+	// the cache routes are only served if an admin token is set
+	var adminToken secret.Secret
+	if token, tokenFile := os.Getenv("CACHE_ADMIN_TOKEN"), os.Getenv("CACHE_ADMIN_TOKEN_FILE"); token != "" || tokenFile != "" {
+		adminToken, err = secret.New(token, tokenFile)
 		if err != nil {
-			fmt.Println(err)
-			return
+			log.Fatalf("error while reading cache admin token: %v", err)
 		}
-		log.Printf("TRACE whole function took %v\n", time.Since(start))
-
-		newerDeployments = resp.Deployments
-		fmt.Printf("len newer deploys: %d\n", len(newerDeployments))
-
-		fmt.Printf("newer deployments response: %+v\n", newerDeployments)
-	}()
-
-	//// -- 2nd call
-	//wg.Add(1)
-	//go func() {
-	//	defer wg.Done()
-	//	resp, err := handler.HttpHandler(context.Background(), cfg, workload)
-	//	if err != nil {
-	//		fmt.Println(err)
-	//		return
-	//	}
-	//	newerDeployments := resp.Deployments
-	//	fmt.Printf("2nd time to test cache: deployments response: %+v\n", newerDeployments)
-	//}()
-
-	wg.Wait()
-
-	// 1. Initialize your data
-	res := Response{
-		Deployments: newerDeployments,
-		Size:        len(newerDeployments),
 	}
 
-	// 2. Marshal to JSON (returns []byte)
-	jsonData, err := json.Marshal(res)
-	if err != nil {
-		log.Fatalf("Error marshaling JSON: %v", err)
+	mux := http.NewServeMux()
+	handler.NewDeploymentsHandler(deploymentService, adminToken).Register(mux)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	// 3. Convert []byte to string
-	jsonString := string(jsonData)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	fmt.Printf("%s", jsonString)
+	// closed once in-flight requests are drained, the deployment client must not be closed before
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("error while shutting down server: %v\n", err)
+		}
+	}()
 
+	log.Printf("listening on %s\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("server failed: %v", err)
+	}
+	<-shutdownDone
 }
 
 func SetupConfig() *config.Config {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		cfg, err := config.Load(path)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		overrideSecrets(cfg)
+		return cfg
+	}
+
 	// setup github
-	return &config.Config{
+	cfg := &config.Config{
 		Owner:    os.Getenv("OWNER"),
 		Env:      os.Getenv("ENVIRONMENT"),
 		Token:    os.Getenv("GITHUB_PAT"),
 		Enabled:  true,
 		Provider: "github",
+		BaseURL:  os.Getenv("BASE_URL"),
+
+		TokenFile: os.Getenv("GITHUB_PAT_FILE"),
+
+		KubeConfig: os.Getenv("KUBECONFIG"),
+	}
+	if tokens := os.Getenv("GITHUB_PATS"); tokens != "" {
+		cfg.Tokens = strings.Split(tokens, ",")
+	}
+	if appID, err := strconv.ParseInt(os.Getenv("GITHUB_APP_ID"), 10, 64); err == nil {
+		cfg.AppID = appID
+	}
+	cfg.AppPrivateKeyPath = os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH")
+	if installationID, err := strconv.ParseInt(os.Getenv("GITHUB_APP_INSTALLATION_ID"), 10, 64); err == nil {
+		cfg.AppInstallationID = installationID
+	}
+	cfg.UploadURL = os.Getenv("UPLOAD_URL")
+	cfg.CABundlePath = os.Getenv("CA_BUNDLE_PATH")
+	cfg.ProxyURL = os.Getenv("PROXY_URL")
+	if timeout, err := time.ParseDuration(os.Getenv("CONNECT_TIMEOUT")); err == nil {
+		cfg.ConnectTimeout = timeout
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT")); err == nil {
+		cfg.RequestTimeout = timeout
+	}
+	cfg.WorkloadAnnotations = strings.ToLower(os.Getenv("WORKLOAD_ANNOTATIONS")) == "true"
+	if settings := os.Getenv("PROVIDER_SETTINGS"); settings != "" {
+		if err := json.Unmarshal([]byte(settings), &cfg.ProviderSettings); err != nil {
+			log.Fatalf("invalid PROVIDER_SETTINGS: %v", err)
+		}
+	}
+	// the settings of the built-in providers
+	setSettings(cfg, "argocd", envSettings(map[string]string{"url": "ARGOCD_URL", "token": "ARGOCD_TOKEN", "tokenFile": "ARGOCD_TOKEN_FILE"}))
+	setSettings(cfg, "flux", envSettings(map[string]string{"namespace": "FLUX_NAMESPACE"}))
+	setSettings(cfg, "git", envSettings(map[string]string{"repoPath": "GIT_REPO_PATH", "deployPattern": "DEPLOY_PATTERN", "deployNotesRef": "DEPLOY_NOTES_REF"}))
+	setSettings(cfg, "file", envSettings(map[string]string{"path": "DEPLOYMENTS_FILE"}))
+	if providers := os.Getenv("PROVIDERS"); providers != "" {
+		cfg.Providers = strings.Split(providers, ",")
+	}
+	if provider := os.Getenv("PROVIDER"); provider != "" {
+		cfg.Provider = provider
+	}
+
+	if size, err := strconv.Atoi(os.Getenv("CACHE_SIZE")); err == nil {
+		cfg.CacheSize = size
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("CACHE_TTL")); err == nil {
+		cfg.CacheTTL = ttl
+	}
+	cfg.CachePath = os.Getenv("CACHE_PATH")
+
+	cfg.RateLimitWait = strings.ToLower(os.Getenv("RATE_LIMIT_WAIT")) == "true"
+	if maxWait, err := time.ParseDuration(os.Getenv("RATE_LIMIT_MAX_WAIT")); err == nil {
+		cfg.RateLimitMaxWait = maxWait
+	}
+	if retries, err := strconv.Atoi(os.Getenv("MAX_RETRIES")); err == nil {
+		cfg.MaxRetries = retries
+	}
+	if concurrency, err := strconv.Atoi(os.Getenv("MAX_CONCURRENCY")); err == nil {
+		cfg.MaxConcurrency = concurrency
+	}
+	if margin, err := time.ParseDuration(os.Getenv("LOAD_MARGIN")); err == nil {
+		cfg.LoadMargin = margin
+	}
+	return cfg
+}
+
+// overrideSecrets replaces the secrets of a config file by the ones set in env vars,
+// so secrets need not be written into the file
+func overrideSecrets(cfg *config.Config) {
+	if token, ok := os.LookupEnv("GITHUB_PAT"); ok {
+		cfg.Token = token
+	}
+	if tokenFile, ok := os.LookupEnv("GITHUB_PAT_FILE"); ok {
+		cfg.TokenFile = tokenFile
+	}
+	if tokens := os.Getenv("GITHUB_PATS"); tokens != "" {
+		cfg.Tokens = strings.Split(tokens, ",")
+	}
+	if path, ok := os.LookupEnv("GITHUB_APP_PRIVATE_KEY_PATH"); ok {
+		cfg.AppPrivateKeyPath = path
+	}
+	values := make(map[string]string)
+	if token, ok := os.LookupEnv("ARGOCD_TOKEN"); ok {
+		values["token"] = token
+	}
+	if tokenFile, ok := os.LookupEnv("ARGOCD_TOKEN_FILE"); ok {
+		values["tokenFile"] = tokenFile
+	}
+	setSettings(cfg, "argocd", values)
+}
+
+// envSettings returns the values of the env vars by setting, leaving out the unset ones
+func envSettings(vars map[string]string) map[string]string {
+	values := make(map[string]string)
+	for setting, env := range vars {
+		if value := os.Getenv(env); value != "" {
+			values[setting] = value
+		}
+	}
+	return values
+}
+
+// setSettings sets values in the settings of the provider name, keeping its other settings
+func setSettings(cfg *config.Config, name string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	settings := make(map[string]any)
+	if raw, ok := cfg.ProviderSettings[name]; ok {
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			log.Fatalf("invalid settings of provider %s: %v", name, err)
+		}
+	}
+	for setting, value := range values {
+		settings[setting] = value
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		log.Fatalf("invalid settings of provider %s: %v", name, err)
+	}
+	if cfg.ProviderSettings == nil {
+		cfg.ProviderSettings = make(map[string]json.RawMessage)
 	}
+	cfg.ProviderSettings[name] = raw
 }
@@ -5,4 +5,10 @@ import "time"
 type DeploymentsQuery struct {
 	From, To                     time.Time
 	Cluster, Namespace, Workload string
+
+	// Owner and Repo select the repository of the workload.
+	// An empty Owner is the owner from the config.
+	Owner, Repo string
+	// Environment overrides the environment from the config for this repository
+	Environment string
 }
@@ -0,0 +1,96 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kemonprogrammer/github-go-client/log"
+)
+
+// Secret is a credential which is read when it is used, so rotated credentials are picked up
+// without a restart. Values are redacted from all log output.
+type Secret interface {
+	Value() (string, error)
+}
+
+// New returns the secret of the file at path, e.g. a mounted Kubernetes secret, or value if path is empty
+func New(value, path string) (Secret, error) {
+	if path != "" {
+		return NewFile(path)
+	}
+	return Static(value), nil
+}
+
+type static string
+
+// Static returns a secret which never changes
+func Static(value string) Secret {
+	log.AddSecret(value)
+	return static(value)
+}
+
+func (s static) Value() (string, error) {
+	return string(s), nil
+}
+
+// File is a secret read from a file, which is read again once its modification time or size changed.
+// Surrounding whitespace is trimmed. It is safe for concurrent use.
+type File struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	value   string
+	loaded  bool
+}
+
+// NewFile returns the secret of the file at path, failing if it can't be read
+func NewFile(path string) (*File, error) {
+	f := &File{path: path}
+	if _, err := f.Value(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Value returns the content of the file. If a changed file can't be read the previous content is kept.
+func (f *File) Value() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.keepLoaded(fmt.Errorf("error while reading secret file: %w", err))
+	}
+	if f.loaded && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.value, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.keepLoaded(fmt.Errorf("error while reading secret file: %w", err))
+	}
+	value := strings.TrimSpace(string(data))
+	log.AddSecret(value)
+
+	if f.loaded && value != f.value {
+		log.Infof("secret %s rotated", f.path)
+	}
+	f.value = value
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.loaded = true
+	return value, nil
+}
+
+func (f *File) keepLoaded(err error) (string, error) {
+	if !f.loaded {
+		return "", err
+	}
+	log.Warnf("keeping previous secret: %v", err)
+	return f.value, nil
+}